@@ -1,15 +1,24 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"rest_service/internal/config"
 	"rest_service/internal/db"
 	"rest_service/internal/handlers"
+	"rest_service/internal/logging"
+	"rest_service/internal/metrics"
 	"rest_service/internal/subscriptionService"
+	"rest_service/internal/tracing"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -27,48 +36,123 @@ import (
 // @BasePath        /
 func main() {
 
-	projectRoot, err := os.Getwd()
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Ошибка получения текущей директории:", err)
+		fatal("could not load config", err)
 	}
 
-	// Поднимаемся на уровень выше, если мы в cmd/
-	if filepath.Base(projectRoot) == "cmd" {
-		projectRoot = filepath.Dir(projectRoot)
+	logger := logging.New(cfg.Log.Level)
+	slog.SetDefault(logger)
+	if cfg.Log.Level != "debug" {
+		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Загружаем .env из корня проекта
-	envPath := filepath.Join(projectRoot, ".env")
-	if err := godotenv.Load(envPath); err != nil {
-		log.Println("Не удалось загрузить .env файл:", err)
-		// Не прерываем выполнение, возможно переменные заданы в системе
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg.DB, os.Args[2:])
+		return
 	}
 
-	db, err := db.InitDB()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("could not connect database: %v", err)
+		fatal("could not set up tracing", err)
+	}
+
+	db, err := db.InitDB(cfg.DB)
+	if err != nil {
+		fatal("could not connect database", err)
+	}
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		fatal("could not register gorm tracing", err)
 	}
 
 	subsRepo := subscriptionService.NewSubscriptionRepository(db)
-	subsService := subscriptionService.NewSubscriptionService(subsRepo)
+	subsService := subscriptionService.NewSubscriptionService(subsRepo,
+		subscriptionService.WithPurgeRetention(cfg.Subscriptions.PurgeRetention()))
 	subsHadlers := handlers.NewSubscriptionHadler(subsService)
+	healthHandler := handlers.NewHealthHandler(db, cfg.Health.PingTimeout)
 
-	r := gin.Default()
-	r.Use(gin.Logger())
+	appMetrics := metrics.New()
+	if err := db.Use(appMetrics.GormPlugin()); err != nil {
+		fatal("could not register gorm metrics", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		appMetrics.RegisterDBStats(sqlDB)
+	}
+	appMetrics.RegisterBusiness(subsService)
+
+	r := gin.New()
+	r.Use(handlers.StreamAbort())
 	r.Use(gin.Recovery())
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	r.Use(logging.GinMiddleware(logger))
+	r.Use(appMetrics.GinMiddleware())
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
+	r.GET("/health/live", healthHandler.Live)
+	r.GET("/health/ready", healthHandler.Ready)
+	r.GET("/metrics", gin.WrapH(appMetrics.Handler()))
+
+	// Обычные запросы к API и долгие (выгрузка, импорт, пакеты) ограничены разными таймаутами
+	api := r.Group("", handlers.Timeout(cfg.HTTP.RequestTimeout))
+	long := r.Group("", handlers.Timeout(cfg.HTTP.LongRequestTimeout))
+
+	subsHadlers.RegisterRoutes(api, long)
+
+	server := &http.Server{
+		Addr:              cfg.HTTP.Addr(),
+		Handler:           handlers.CustomMethods(r),
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
 
-	r.GET("/subscriptions", subsHadlers.ListSubscriptions)
-	r.POST("/subscriptions", subsHadlers.CreateSubscription)
-	r.GET("/subscriptions/:id", subsHadlers.GetSubscriptionByID)
-	r.PUT("/subscriptions/:id", subsHadlers.UpdateSubscriptionByID)
-	r.DELETE("/subscriptions/:id", subsHadlers.DeleteSubcriptionByID)
-	r.GET("/subscriptions/amountSubscriptions", subsHadlers.GetAmountOfsubscriptions)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Сервер слушает", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal("could not start server", err)
+		}
+	case <-ctx.Done():
+		stop() // повторный сигнал завершит процесс сразу
+		slog.Info("Получен сигнал остановки, ждем завершения запросов", "timeout", cfg.HTTP.ShutdownTimeout.String())
+
+		// Пока балансировщик не увидел 503 на /health/ready, продолжаем принимать запросы
+		healthHandler.StartDraining()
+		time.Sleep(cfg.Health.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Не все запросы завершились вовремя", "error", err)
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("Ошибка закрытия пула соединений", "error", err)
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("Не удалось отправить оставшиеся спаны", "error", err)
+	}
+	slog.Info("Сервер остановлен")
+}
 
-	r.Run(":8081")
+// fatal пишет ошибку запуска в лог и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
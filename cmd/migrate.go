@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"rest_service/internal/config"
+	"rest_service/internal/db"
+	"rest_service/internal/migrations"
+	"strconv"
+)
+
+var errMigrateUsage = errors.New("использование: gin-app migrate up | down [N] | status")
+
+// runMigrate выполняет подкоманду migrate:
+//
+//	migrate up        — применить все непримененные миграции
+//	migrate down [N]  — откатить N последних миграций (по умолчанию одну)
+//	migrate status    — показать текущую версию и непримененные миграции
+func runMigrate(cfg config.DBConfig, args []string) {
+	if len(args) == 0 {
+		fatal("invalid migrate arguments", errMigrateUsage)
+	}
+
+	conn, err := db.Connect(cfg)
+	if err != nil {
+		fatal("could not connect database", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(conn)
+		for _, m := range applied {
+			fmt.Printf("применена %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fatal("migration failed", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("схема уже актуальна")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fatal("invalid migrate arguments", fmt.Errorf("некорректное число миграций: %q", args[1]))
+			}
+		}
+		reverted, err := migrations.Down(conn, steps)
+		for _, m := range reverted {
+			fmt.Printf("откачена %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fatal("rollback failed", err)
+		}
+
+	case "status":
+		status, err := migrations.GetStatus(conn)
+		if err != nil {
+			fatal("could not get migration status", err)
+		}
+		fmt.Printf("текущая версия: %d\n", status.Current)
+		for _, m := range status.Pending {
+			fmt.Printf("ожидает: %04d_%s\n", m.Version, m.Name)
+		}
+
+	default:
+		fatal("invalid migrate arguments", errMigrateUsage)
+	}
+}
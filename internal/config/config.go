@@ -0,0 +1,390 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config — все настройки сервиса. Значения берутся по возрастанию приоритета:
+// значения по умолчанию, YAML-файл, .env, переменные окружения.
+// В YAML длительности записываются строками: "5s", "30m".
+type Config struct {
+	HTTP          HTTPConfig          `yaml:"http"`
+	Log           LogConfig           `yaml:"log"`
+	DB            DBConfig            `yaml:"db"`
+	Health        HealthConfig        `yaml:"health"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	Subscriptions SubscriptionsConfig `yaml:"subscriptions"`
+}
+
+type HTTPConfig struct {
+	Port int `yaml:"port"` // HTTP_PORT
+
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // HTTP_READ_TIMEOUT
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // HTTP_READ_HEADER_TIMEOUT
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // HTTP_WRITE_TIMEOUT, должен покрывать самую долгую выгрузку
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // HTTP_IDLE_TIMEOUT
+	// RequestTimeout — сколько может обрабатываться обычный запрос к API, 0 — без ограничения (HTTP_REQUEST_TIMEOUT)
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// LongRequestTimeout — то же для выгрузки, импорта и пакетных операций (HTTP_LONG_REQUEST_TIMEOUT)
+	LongRequestTimeout time.Duration `yaml:"long_request_timeout"`
+	// ShutdownTimeout — сколько ждать завершения текущих запросов после SIGTERM (HTTP_SHUTDOWN_TIMEOUT)
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"` // LOG_LEVEL: debug, info, warn, error
+}
+
+type DBConfig struct {
+	Host     string `yaml:"host"`     // DB_HOST
+	Port     int    `yaml:"port"`     // DB_PORT
+	User     string `yaml:"user"`     // DB_USER
+	Password string `yaml:"password"` // DB_PASSWORD
+	Name     string `yaml:"name"`     // DB_NAME
+	SSLMode  string `yaml:"sslmode"`  // DB_SSLMODE
+
+	// TransactionMode — уровень изоляции транзакций по умолчанию (PG_TRANSACTION_MODE)
+	TransactionMode string `yaml:"transaction_mode"`
+	// LogLevel — уровень логирования SQL в gorm: silent, error, warn, info (DB_LOG_LEVEL).
+	// Текст всех запросов (info) попадает в лог, только если и LOG_LEVEL=debug.
+	LogLevel string `yaml:"log_level"`
+
+	RetryInterval    time.Duration `yaml:"retry_interval"`     // DB_RETRY_INTERVAL, пауза перед первой повторной попыткой
+	MaxRetries       int           `yaml:"max_retries"`        // DB_MAX_RETRIES
+	MaxOpenConns     int           `yaml:"max_open_conns"`     // DB_MAX_OPEN_CONNS
+	MaxIdleConns     int           `yaml:"max_idle_conns"`     // DB_MAX_IDLE_CONNS
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime"`  // DB_CONN_MAX_LIFETIME
+	ConnMaxIdleTime  time.Duration `yaml:"conn_max_idle_time"` // DB_CONN_MAX_IDLE_TIME
+	StatementTimeout time.Duration `yaml:"statement_timeout"`  // DB_STATEMENT_TIMEOUT, 0 — без ограничения
+}
+
+type HealthConfig struct {
+	// PingTimeout — сколько ждать ответа базы при проверке готовности (HEALTH_PING_TIMEOUT)
+	PingTimeout time.Duration `yaml:"ping_timeout"`
+	// DrainDelay — сколько после SIGTERM отвечать 503 на /health/ready, прежде чем
+	// перестать принимать соединения; дает балансировщику время убрать реплику (HEALTH_DRAIN_DELAY)
+	DrainDelay time.Duration `yaml:"drain_delay"`
+}
+
+// Экспортеры трассировки
+const (
+	TracingExporterNone = "none"
+	TracingExporterOTLP = "otlp"
+)
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // OTEL_TRACES_EXPORTER: none или otlp
+	ServiceName string  `yaml:"service_name"` // OTEL_SERVICE_NAME
+	SampleRatio float64 `yaml:"sample_ratio"` // OTEL_TRACES_SAMPLER_ARG, доля трассируемых запросов от 0 до 1
+}
+
+type SubscriptionsConfig struct {
+	// SoftDeleteRetentionDays — сколько дней хранить удаленные подписки (SOFT_DELETE_RETENTION_DAYS)
+	SoftDeleteRetentionDays int `yaml:"soft_delete_retention_days"`
+}
+
+// Допустимые значения перечислимых настроек
+var (
+	sslModes         = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	transactionModes = []string{"read uncommitted", "read committed", "repeatable read", "serializable"}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+	dbLogLevels      = []string{"silent", "error", "warn", "info"}
+	tracingExporters = []string{TracingExporterNone, TracingExporterOTLP}
+)
+
+// Default возвращает конфигурацию по умолчанию
+func Default() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Port:               8081,
+			ReadTimeout:        15 * time.Second,
+			ReadHeaderTimeout:  5 * time.Second,
+			WriteTimeout:       60 * time.Second,
+			IdleTimeout:        2 * time.Minute,
+			RequestTimeout:     10 * time.Second,
+			LongRequestTimeout: 55 * time.Second,
+			ShutdownTimeout:    20 * time.Second,
+		},
+		Log: LogConfig{Level: "info"},
+		DB: DBConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			TransactionMode: "read committed",
+			LogLevel:        "warn",
+			RetryInterval:   2 * time.Second,
+			MaxRetries:      5,
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Health: HealthConfig{PingTimeout: 2 * time.Second, DrainDelay: 5 * time.Second},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			ServiceName: "subscription-service",
+			SampleRatio: 1,
+		},
+		Subscriptions: SubscriptionsConfig{SoftDeleteRetentionDays: 30},
+	}
+}
+
+// Load собирает конфигурацию. YAML читается из CONFIG_FILE, а если переменная
+// не задана — из config.yaml в корне проекта, когда такой файл есть.
+// .env тоже ищется в корне проекта и не перекрывает уже заданные переменные.
+func Load() (Config, error) {
+	cfg := Default()
+	root := projectRoot()
+
+	configFile, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		configFile = filepath.Join(root, "config.yaml")
+	}
+	if err := loadYAML(&cfg, configFile, explicit); err != nil {
+		return Config{}, err
+	}
+
+	if err := godotenv.Load(filepath.Join(root, ".env")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("ошибка загрузки .env: %w", err)
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки сразу
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Port > 0 && c.HTTP.Port <= 65535, "http.port должен быть от 1 до 65535, получено %d", c.HTTP.Port)
+	check(c.HTTP.ReadTimeout >= 0, "http.read_timeout не может быть отрицательным")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout не может быть отрицательным")
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout не может быть отрицательным")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle_timeout не может быть отрицательным")
+	check(c.HTTP.RequestTimeout >= 0, "http.request_timeout не может быть отрицательным")
+	check(c.HTTP.LongRequestTimeout >= 0, "http.long_request_timeout не может быть отрицательным")
+	check(c.HTTP.WriteTimeout == 0 || c.HTTP.RequestTimeout < c.HTTP.WriteTimeout,
+		"http.request_timeout (%s) должен быть меньше http.write_timeout (%s), иначе ответ о таймауте не успеет уйти",
+		c.HTTP.RequestTimeout, c.HTTP.WriteTimeout)
+	check(c.HTTP.WriteTimeout == 0 || c.HTTP.LongRequestTimeout < c.HTTP.WriteTimeout,
+		"http.long_request_timeout (%s) должен быть меньше http.write_timeout (%s), иначе ответ о таймауте не успеет уйти",
+		c.HTTP.LongRequestTimeout, c.HTTP.WriteTimeout)
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout должен быть положительным")
+
+	check(slices.Contains(logLevels, c.Log.Level), "log.level должен быть одним из %v, получено %q", logLevels, c.Log.Level)
+
+	check(c.DB.Host != "", "db.host не задан")
+	check(c.DB.Port > 0 && c.DB.Port <= 65535, "db.port должен быть от 1 до 65535, получено %d", c.DB.Port)
+	check(c.DB.User != "", "db.user не задан")
+	check(c.DB.Name != "", "db.name не задан")
+	check(slices.Contains(sslModes, c.DB.SSLMode), "db.sslmode должен быть одним из %v, получено %q", sslModes, c.DB.SSLMode)
+	check(slices.Contains(transactionModes, c.DB.TransactionMode), "db.transaction_mode должен быть одним из %v, получено %q", transactionModes, c.DB.TransactionMode)
+	check(slices.Contains(dbLogLevels, c.DB.LogLevel), "db.log_level должен быть одним из %v, получено %q", dbLogLevels, c.DB.LogLevel)
+	check(c.DB.RetryInterval >= 0, "db.retry_interval не может быть отрицательным")
+	check(c.DB.MaxRetries >= 0, "db.max_retries не может быть отрицательным")
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns не может быть отрицательным")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns не может быть отрицательным")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"db.max_idle_conns (%d) не может быть больше db.max_open_conns (%d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime не может быть отрицательным")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time не может быть отрицательным")
+	check(c.DB.StatementTimeout >= 0, "db.statement_timeout не может быть отрицательным")
+	check(c.DB.StatementTimeout == 0 || c.DB.StatementTimeout >= c.HTTP.LongRequestTimeout,
+		"db.statement_timeout (%s) должен быть не меньше http.long_request_timeout (%s), иначе база прервет выгрузку раньше таймаута запроса",
+		c.DB.StatementTimeout, c.HTTP.LongRequestTimeout)
+
+	check(c.Health.PingTimeout > 0, "health.ping_timeout должен быть положительным")
+	check(c.Health.DrainDelay >= 0, "health.drain_delay не может быть отрицательным")
+
+	check(slices.Contains(tracingExporters, c.Tracing.Exporter), "tracing.exporter должен быть одним из %v, получено %q", tracingExporters, c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "tracing.service_name не задан")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio должен быть от 0 до 1, получено %g", c.Tracing.SampleRatio)
+
+	check(c.Subscriptions.SoftDeleteRetentionDays >= 0, "subscriptions.soft_delete_retention_days не может быть отрицательным")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("некорректная конфигурация: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Addr — адрес, который слушает HTTP-сервер
+func (c HTTPConfig) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// DSN собирает строку подключения к Postgres в формате key=value libpq.
+// Уровень изоляции и таймаут запросов передаются как параметры сессии.
+func (c DBConfig) DSN() string {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		dsnValue(c.Host), dsnValue(c.User), dsnValue(c.Password), dsnValue(c.Name), c.Port, dsnValue(c.SSLMode))
+	dsn += " default_transaction_isolation=" + dsnValue(c.TransactionMode)
+	if c.StatementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", c.StatementTimeout.Milliseconds())
+	}
+	return dsn
+}
+
+// dsnEscaper экранирует обратную косую черту и кавычку, как требует libpq
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// dsnValue берет значение в одинарные кавычки, чтобы пробелы и спецсимволы
+// (например, в пароле) не ломали строку подключения
+func dsnValue(value string) string {
+	return "'" + dsnEscaper.Replace(value) + "'"
+}
+
+// PurgeRetention — срок хранения удаленных подписок
+func (c SubscriptionsConfig) PurgeRetention() time.Duration {
+	return time.Duration(c.SoftDeleteRetentionDays) * 24 * time.Hour
+}
+
+func loadYAML(cfg *Config, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("ошибка разбора %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv переносит заданные переменные окружения поверх конфигурации
+func applyEnv(cfg *Config) error {
+	texts := map[string]*string{
+		"LOG_LEVEL":            &cfg.Log.Level,
+		"DB_HOST":              &cfg.DB.Host,
+		"DB_USER":              &cfg.DB.User,
+		"DB_PASSWORD":          &cfg.DB.Password,
+		"DB_NAME":              &cfg.DB.Name,
+		"DB_SSLMODE":           &cfg.DB.SSLMode,
+		"PG_TRANSACTION_MODE":  &cfg.DB.TransactionMode,
+		"DB_LOG_LEVEL":         &cfg.DB.LogLevel,
+		"OTEL_TRACES_EXPORTER": &cfg.Tracing.Exporter,
+		"OTEL_SERVICE_NAME":    &cfg.Tracing.ServiceName,
+	}
+	ints := map[string]*int{
+		"HTTP_PORT":                  &cfg.HTTP.Port,
+		"DB_PORT":                    &cfg.DB.Port,
+		"DB_MAX_RETRIES":             &cfg.DB.MaxRetries,
+		"DB_MAX_OPEN_CONNS":          &cfg.DB.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS":          &cfg.DB.MaxIdleConns,
+		"SOFT_DELETE_RETENTION_DAYS": &cfg.Subscriptions.SoftDeleteRetentionDays,
+	}
+	durations := map[string]*time.Duration{
+		"HTTP_READ_TIMEOUT":         &cfg.HTTP.ReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT":  &cfg.HTTP.ReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":        &cfg.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":         &cfg.HTTP.IdleTimeout,
+		"HTTP_REQUEST_TIMEOUT":      &cfg.HTTP.RequestTimeout,
+		"HTTP_LONG_REQUEST_TIMEOUT": &cfg.HTTP.LongRequestTimeout,
+		"HTTP_SHUTDOWN_TIMEOUT":     &cfg.HTTP.ShutdownTimeout,
+		"DB_RETRY_INTERVAL":         &cfg.DB.RetryInterval,
+		"DB_CONN_MAX_LIFETIME":      &cfg.DB.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME":     &cfg.DB.ConnMaxIdleTime,
+		"DB_STATEMENT_TIMEOUT":      &cfg.DB.StatementTimeout,
+		"HEALTH_PING_TIMEOUT":       &cfg.Health.PingTimeout,
+		"HEALTH_DRAIN_DELAY":        &cfg.Health.DrainDelay,
+	}
+
+	floats := map[string]*float64{
+		"OTEL_TRACES_SAMPLER_ARG": &cfg.Tracing.SampleRatio,
+	}
+
+	var errs []error
+	for name, target := range texts {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+	for name, target := range ints {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s должен быть целым числом, получено %q", name, value))
+			continue
+		}
+		*target = parsed
+	}
+	for name, target := range floats {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s должен быть числом, получено %q", name, value))
+			continue
+		}
+		*target = parsed
+	}
+	for name, target := range durations {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := parseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s должен быть числом секунд или длительностью вида 500ms, получено %q", name, value))
+			continue
+		}
+		*target = parsed
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("некорректная конфигурация: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// parseDuration принимает число секунд ("5") или строку time.ParseDuration ("500ms", "1m")
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// projectRoot — текущая директория; если запущены из cmd/, поднимаемся на уровень выше
+func projectRoot() string {
+	root, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	if filepath.Base(root) == "cmd" {
+		root = filepath.Dir(root)
+	}
+	return root
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// clearEnv убирает из окружения переменные конфигурации на время теста
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"CONFIG_FILE", "LOG_LEVEL", "HTTP_PORT", "HTTP_READ_TIMEOUT", "HTTP_READ_HEADER_TIMEOUT", "HTTP_WRITE_TIMEOUT",
+		"HTTP_IDLE_TIMEOUT", "HTTP_REQUEST_TIMEOUT", "HTTP_LONG_REQUEST_TIMEOUT", "HTTP_SHUTDOWN_TIMEOUT", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME",
+		"DB_SSLMODE", "PG_TRANSACTION_MODE", "DB_LOG_LEVEL", "DB_RETRY_INTERVAL", "DB_MAX_RETRIES",
+		"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME",
+		"DB_STATEMENT_TIMEOUT", "HEALTH_PING_TIMEOUT", "HEALTH_DRAIN_DELAY", "OTEL_TRACES_EXPORTER",
+		"OTEL_SERVICE_NAME", "OTEL_TRACES_SAMPLER_ARG", "SOFT_DELETE_RETENTION_DAYS",
+	} {
+		if value, ok := os.LookupEnv(name); ok {
+			os.Unsetenv(name)
+			t.Cleanup(func() { os.Setenv(name, value) })
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	yaml := "http:\n  port: 9000\ndb:\n  user: yaml_user\n  name: yaml_db\n  statement_timeout: 90s\n"
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("DB_NAME", "env_db")
+	t.Setenv("DB_RETRY_INTERVAL", "5")
+	t.Setenv("PG_TRANSACTION_MODE", "repeatable read")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.HTTP.Port != 9000 {
+		t.Errorf("http.port = %d, ожидалось значение из YAML 9000", cfg.HTTP.Port)
+	}
+	if cfg.DB.User != "yaml_user" || cfg.DB.Name != "env_db" {
+		t.Errorf("db.user/db.name = %q/%q, ожидалось yaml_user/env_db", cfg.DB.User, cfg.DB.Name)
+	}
+	if cfg.DB.RetryInterval != 5*time.Second || cfg.DB.StatementTimeout != 90*time.Second {
+		t.Errorf("длительности = %s/%s, ожидалось 5s/90s", cfg.DB.RetryInterval, cfg.DB.StatementTimeout)
+	}
+	if cfg.DB.MaxOpenConns != Default().DB.MaxOpenConns {
+		t.Errorf("db.max_open_conns = %d, ожидалось значение по умолчанию", cfg.DB.MaxOpenConns)
+	}
+	if cfg.Health.DrainDelay <= 0 {
+		t.Errorf("health.drain_delay = %s, по умолчанию готовность должна успеть ответить 503 до остановки", cfg.Health.DrainDelay)
+	}
+
+	dsn := cfg.DB.DSN()
+	for _, part := range []string{"dbname='env_db'", "default_transaction_isolation='repeatable read'", "statement_timeout=90000"} {
+		if !strings.Contains(dsn, part) {
+			t.Errorf("DSN %q не содержит %q", dsn, part)
+		}
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.HTTP.Port = 0
+	cfg.DB.User = ""
+	cfg.DB.Name = "db"
+	cfg.DB.TransactionMode = "chaos"
+	cfg.DB.MaxIdleConns = 100
+	cfg.DB.StatementTimeout = 30 * time.Second
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("ожидалась ошибка валидации")
+	}
+	for _, field := range []string{"http.port", "db.user", "db.transaction_mode", "db.max_idle_conns", "db.statement_timeout"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("в ошибке нет %s: %v", field, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownYAMLFields(t *testing.T) {
+	clearEnv(t)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("db:\n  hots: typo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+
+	if _, err := Load(); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного поля hots")
+	}
+}
+
+func TestDSNEscapesValues(t *testing.T) {
+	cfg := Default()
+	cfg.DB.User = "app user"
+	cfg.DB.Password = `p@ss 'word' \ x`
+	cfg.DB.Name = "subs"
+	cfg.DB.StatementTimeout = 0
+
+	parsed, err := pgconn.ParseConfig(cfg.DB.DSN())
+	if err != nil {
+		t.Fatalf("DSN не разбирается: %v", err)
+	}
+	if parsed.User != cfg.DB.User || parsed.Password != cfg.DB.Password || parsed.Database != "subs" {
+		t.Errorf("user/password/dbname = %q/%q/%q", parsed.User, parsed.Password, parsed.Database)
+	}
+	if got := parsed.RuntimeParams["default_transaction_isolation"]; got != cfg.DB.TransactionMode {
+		t.Errorf("default_transaction_isolation = %q, ожидали %q", got, cfg.DB.TransactionMode)
+	}
+}
@@ -2,49 +2,82 @@ package db
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"time"
 
-	subscriptionService "rest_service/internal/subscriptionService"
+	"rest_service/internal/config"
+	"rest_service/internal/logging"
+	"rest_service/internal/migrations"
 
-	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var db *gorm.DB
-
-func InitDB() (*gorm.DB, error) {
+// InitDB подключается к базе и применяет непримененные миграции
+func InitDB(cfg config.DBConfig) (*gorm.DB, error) {
+	db, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	err := godotenv.Load()
+	applied, err := migrations.Up(db)
 	if err != nil {
-		log.Fatalf("Ошибка загрузки .env: %v", err)
+		return nil, fmt.Errorf("could not migrate: %w", err)
 	}
+	for _, m := range applied {
+		slog.Info("Применена миграция", "version", m.Version, "name", m.Name)
+	}
+
+	return db, nil
+}
+
+// maxRetryDelay ограничивает рост паузы между попытками подключения
+const maxRetryDelay = time.Minute
 
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	sslmode := os.Getenv("DB_SSLMODE")
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		host, user, password, dbname, port, sslmode)
+// Connect только подключается к базе, без миграций. Если база недоступна,
+// подключение повторяется cfg.MaxRetries раз с растущей паузой.
+func Connect(cfg config.DBConfig) (*gorm.DB, error) {
 
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	gormConfig := &gorm.Config{
+		Logger: logging.GormLogger(logLevels[cfg.LogLevel]),
+	}
 
-	db.Logger = logger.Default.LogMode(logger.Info)
+	var (
+		db  *gorm.DB
+		err error
+	)
+	delay := cfg.RetryInterval
+	for attempt := 0; ; attempt++ {
+		db, err = gorm.Open(postgres.Open(cfg.DSN()), gormConfig)
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.MaxRetries {
+			return nil, fmt.Errorf("could not connect to database after %d attempts: %w", attempt+1, err)
+		}
 
-	if err != nil {
-		log.Fatalf("could not connect to database: %v", err)
+		slog.Warn("Не удалось подключиться к базе, повторяем",
+			"attempt", attempt+1, "max_attempts", cfg.MaxRetries+1, "retry_in", delay.String(), "error", err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
 	}
 
-	if err := db.AutoMigrate(&subscriptionService.Subscription{}); err != nil {
-		log.Fatalf("could not migrate: %v", err)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return db, nil
 
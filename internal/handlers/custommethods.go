@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// batchRoutePath — внутренний путь, под которым зарегистрирован POST /subscriptions:batch
+const batchRoutePath = "/subscriptions/batch"
+
+// customMethodRoutes сопоставляет пользовательские методы в стиле AIP-136
+// (POST /subscriptions:batch) с внутренними путями маршрутизатора. gin считает
+// двоеточие в пути началом параметра, поэтому такие маршруты нельзя
+// зарегистрировать как есть.
+var customMethodRoutes = map[string]string{
+	"/subscriptions:batch": batchRoutePath,
+}
+
+// CustomMethods оборачивает маршрутизатор: переписывает пути известных
+// пользовательских методов во внутренние, а на неизвестный метод тех же
+// ресурсов (POST /subscriptions:merge) и на прямое обращение к внутреннему
+// пути отвечает 404. Остальные пути, в том числе с двоеточием
+// (GET /subscriptions/1:2), уходят в маршрутизатор без изменений.
+func CustomMethods(next http.Handler) http.Handler {
+	internal := make(map[string]bool, len(customMethodRoutes))
+	var resources []string
+	for method, route := range customMethodRoutes {
+		internal[route] = true
+		resource, _, _ := strings.Cut(method, ":")
+		resources = append(resources, resource+":")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := customMethodRoutes[r.URL.Path]; ok {
+			r = r.WithContext(context.WithValue(r.Context(), originalPathKey{}, r.URL.Path))
+			rewritten := *r.URL
+			rewritten.Path, rewritten.RawPath = route, ""
+			r.URL = &rewritten
+			next.ServeHTTP(w, r)
+			return
+		}
+		if internal[r.URL.Path] || hasAnyPrefix(r.URL.Path, resources) {
+			writeRouteNotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type originalPathKey struct{}
+
+// requestPath — путь запроса в том виде, в каком его прислал клиент
+func requestPath(r *http.Request) string {
+	if path, ok := r.Context().Value(originalPathKey{}).(string); ok {
+		return path
+	}
+	return r.URL.Path
+}
+
+// writeRouteNotFound отвечает problem+json до маршрутизатора gin
+func writeRouteNotFound(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(ProblemDetails{
+		Type:     "/problems/" + codeRouteNotFound,
+		Title:    http.StatusText(http.StatusNotFound),
+		Status:   http.StatusNotFound,
+		Instance: r.URL.Path,
+		Code:     codeRouteNotFound,
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	subscriptionService "rest_service/internal/subscriptionService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Коды ошибок, которые возникают на уровне HTTP, до вызова сервиса
+const (
+	codeInvalidRequestBody   = "invalid_request_body"
+	codeInvalidPagination    = "invalid_pagination"
+	codeInvalidIfMatch       = "invalid_if_match"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeRouteNotFound        = "route_not_found"
+	codeNotAcceptable        = "not_acceptable"
+	codeInvalidFieldType     = "invalid_field_type"
+)
+
+// statusClientClosedRequest — нестандартный статус (nginx), которым помечается
+// запрос, отмененный клиентом. Клиент его уже не увидит, но он попадает в логи и метрики.
+const statusClientClosedRequest = 499
+
+// ProblemDetails — тело ответа об ошибке в формате RFC 7807 (application/problem+json)
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Ошибки отдельных полей, если запрос не прошел валидацию
+	Errors []subscriptionService.FieldError `json:"errors,omitempty"`
+}
+
+// statusByCode переопределяет статус для отдельных кодов внутри категории
+var statusByCode = map[string]int{
+	subscriptionService.CodeVersionConflict: http.StatusPreconditionFailed,
+}
+
+// writeError переводит ошибку сервиса в ответ problem+json
+func writeError(c *gin.Context, err error) {
+	problem := problemFor(c, err)
+	writeProblemDetails(c, problem)
+}
+
+// problemFor сопоставляет ошибку сервиса с HTTP-статусом и телом problem+json.
+// Это единственное место, где ошибки сервиса переводятся в HTTP.
+func problemFor(c *gin.Context, err error) ProblemDetails {
+	var domainErr *subscriptionService.Error
+	if !errors.As(err, &domainErr) {
+		// Ошибка не прошла через сервис, например, оборвалась потоковая выгрузка
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return newProblem(c, http.StatusGatewayTimeout, subscriptionService.CodeRequestTimeout, "")
+		case errors.Is(err, context.Canceled):
+			return newProblem(c, statusClientClosedRequest, subscriptionService.CodeRequestCanceled, "")
+		}
+		return newProblem(c, http.StatusInternalServerError, subscriptionService.CodeInternal, "")
+	}
+
+	status, ok := statusByCode[domainErr.Code]
+	if !ok {
+		switch {
+		case errors.Is(domainErr, subscriptionService.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(domainErr, subscriptionService.ErrValidation):
+			status = http.StatusBadRequest
+		case errors.Is(domainErr, subscriptionService.ErrConflict):
+			status = http.StatusConflict
+		case errors.Is(domainErr, subscriptionService.ErrTimeout):
+			status = http.StatusGatewayTimeout
+		case errors.Is(domainErr, subscriptionService.ErrCanceled):
+			status = statusClientClosedRequest
+		default:
+			status = http.StatusInternalServerError
+		}
+	}
+
+	// Подробности внутренних ошибок клиенту не раскрываем
+	detail := domainErr.Message
+	if status == http.StatusInternalServerError {
+		detail = ""
+	}
+	problem := newProblem(c, status, domainErr.Code, detail)
+	problem.Errors = domainErr.Fields
+	return problem
+}
+
+// writeProblem отправляет ответ problem+json с указанным статусом и кодом
+func writeProblem(c *gin.Context, status int, code, detail string) {
+	writeProblemDetails(c, newProblem(c, status, code, detail))
+}
+
+func writeProblemDetails(c *gin.Context, problem ProblemDetails) {
+	c.Header("Content-Type", "application/problem+json; charset=utf-8")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+func newProblem(c *gin.Context, status int, code, detail string) ProblemDetails {
+	title := http.StatusText(status)
+	if status == statusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	return ProblemDetails{
+		Type:     "/problems/" + code,
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: requestPath(c.Request),
+		Code:     code,
+	}
+}
+
+// bindRequestBody разбирает JSON-тело создания или обновления подписки;
+// при ошибке сам отвечает клиенту. Значение не того типа описывается так же,
+// как ошибка валидации, — списком ошибок полей.
+func bindRequestBody(c *gin.Context, logger *slog.Logger, req *subscriptionService.RequestBody) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	logger.Info("Ошибка привязки JSON", "error", err)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		problem := newProblem(c, http.StatusBadRequest, subscriptionService.CodeValidationFailed, typeErr.Field+": ожидается "+typeErr.Type.String())
+		problem.Errors = []subscriptionService.FieldError{{
+			Field:   typeErr.Field,
+			Code:    codeInvalidFieldType,
+			Message: "ожидается " + typeErr.Type.String() + ", получено " + typeErr.Value,
+		}}
+		writeProblemDetails(c, problem)
+		return false
+	}
+
+	writeProblem(c, http.StatusBadRequest, codeInvalidRequestBody, err.Error())
+	return false
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New("некорректный заголовок If-Match")
+
+// etag формирует значение заголовка ETag по версии подписки
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch разбирает заголовок If-Match (RFC 9110 §13.1.1) и возвращает
+// версии, с которыми клиент согласен работать. Пустой заголовок и "*" означают,
+// что клиент не требует конкретной версии (nil).
+//
+// If-Match требует сильного сравнения, поэтому слабые ETag (W/"3") и теги,
+// которые мы не выдавали, не совпадают ни с одной версией. Если подходящих
+// тегов нет совсем, возвращается пустой, но не nil список — обновление
+// получит 412.
+func parseIfMatch(header string) ([]int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' || strings.Contains(tag[1:len(tag)-1], `"`) {
+			return nil, errInvalidIfMatch
+		}
+		if weak {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    []int
+		wantErr bool
+	}{
+		{header: "", want: nil},
+		{header: "*", want: nil},
+		{header: `"3"`, want: []int{3}},
+		{header: ` "3" , "4"`, want: []int{3, 4}},
+		{header: `W/"3"`, want: []int{}},
+		{header: `W/"3", "4"`, want: []int{4}},
+		{header: `"abc"`, want: []int{}},
+		{header: `3`, wantErr: true},
+		{header: `"3", 4`, wantErr: true},
+		{header: `"3",`, wantErr: true},
+		{header: `"3"4"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := parseIfMatch(tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidIfMatch) {
+					t.Fatalf("ожидали errInvalidIfMatch, получили %v, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("parseIfMatch(%q) = %#v, ожидали %#v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	subscriptionService "rest_service/internal/subscriptionService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Форматы выгрузки, между которыми выбирает заголовок Accept
+const (
+	mimeCSV    = "text/csv"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// exportFlushEvery — через сколько строк отправлять накопленные данные клиенту
+const exportFlushEvery = 500
+
+// streamAbortedKey — ключ в gin.Context: потоковый ответ оборван после отправки части данных
+const streamAbortedKey = "stream_aborted"
+
+// abortStream помечает ответ как оборванный. Статус 200 и часть строк уже ушли
+// клиенту, поэтому сообщить об ошибке можно только разрывом соединения — его
+// выполняет StreamAbort после выхода из хендлера.
+func abortStream(c *gin.Context) {
+	c.Set(streamAbortedKey, true)
+	c.Abort()
+}
+
+// StreamAbort разрывает соединение, если хендлер оборвал потоковый ответ
+// (abortStream). Без этого ответ завершается штатно, и клиент принимает
+// обрезанный файл за целый. Подключается первым, до gin.Recovery: паника
+// http.ErrAbortHandler должна дойти до net/http, который закрывает соединение
+// без завершающего чанка (в HTTP/2 — сбрасывает поток).
+func StreamAbort() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.GetBool(streamAbortedKey) {
+			c.Writer.Flush()
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// recordWriter пишет выгрузку построчно в CSV или NDJSON
+type recordWriter interface {
+	// write получает одну и ту же запись в виде строки CSV и значения для NDJSON
+	write(csvRow []string, jsonValue interface{}) error
+	flush() error
+}
+
+func newRecordWriter(format string, w io.Writer, csvHeader []string) (recordWriter, error) {
+	if format == mimeNDJSON {
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) write(csvRow []string, _ interface{}) error {
+	return w.writer.Write(csvRow)
+}
+
+func (w *csvWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) write(_ []string, jsonValue interface{}) error {
+	// Encode сам добавляет перевод строки после каждого объекта
+	return w.encoder.Encode(jsonValue)
+}
+
+func (w *ndjsonWriter) flush() error {
+	return nil
+}
+
+var subscriptionCSVHeader = []string{
+	"id", "service_name", "price", "currency", "user_id", "start_date", "end_date", "created_at", "updated_at", "version",
+}
+
+// subscriptionCSVRow представляет подписку строкой CSV; даты — в формате API (YYYY-MM-DD)
+func subscriptionCSVRow(s subscriptionService.Subscription) []string {
+	endDate := ""
+	if s.EndDate != nil {
+		endDate = s.EndDate.UTC().Format(subscriptionService.DateLayout)
+	}
+	return []string{
+		strconv.FormatUint(uint64(s.ID), 10),
+		s.ServiceName,
+		strconv.Itoa(s.Price),
+		s.Currency,
+		s.UserID.String(),
+		s.StartDate.UTC().Format(subscriptionService.DateLayout),
+		endDate,
+		s.CreatedAt.Format(time.RFC3339),
+		s.UpdatedAt.Format(time.RFC3339),
+		strconv.Itoa(s.Version),
+	}
+}
+
+var costCSVHeader = []string{"group", "month", "currency", "total_price"}
+
+// costExportRow — строка выгрузки суммы подписок: группа и месяц
+type costExportRow struct {
+	Group      string `json:"group"`
+	Month      string `json:"month"`
+	Currency   string `json:"currency"`
+	TotalPrice int    `json:"total_price"`
+}
+
+func (r costExportRow) csvRow() []string {
+	return []string{r.Group, r.Month, r.Currency, strconv.Itoa(r.TotalPrice)}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"rest_service/internal/migrations"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Статусы в ответах проверок здоровья
+const (
+	healthOK          = "ok"
+	healthUnavailable = "unavailable"
+	healthDraining    = "draining"
+)
+
+type HealthHandler struct {
+	db          *gorm.DB
+	pingTimeout time.Duration
+	draining    atomic.Bool
+}
+
+func NewHealthHandler(db *gorm.DB, pingTimeout time.Duration) *HealthHandler {
+	return &HealthHandler{db: db, pingTimeout: pingTimeout}
+}
+
+// StartDraining переводит готовность в 503: сервис завершается и новых запросов не ждет
+func (h *HealthHandler) StartDraining() {
+	h.draining.Store(true)
+}
+
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
+
+type ReadinessResponse struct {
+	Status     string            `json:"status" enums:"ok,unavailable,draining"`
+	Database   DatabaseCheck     `json:"database"`
+	Migrations MigrationsCheck   `json:"migrations"`
+	Pool       PoolStatsResponse `json:"pool"`
+}
+
+type DatabaseCheck struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type MigrationsCheck struct {
+	Status  string `json:"status"`
+	Current int    `json:"current"`
+	Latest  int    `json:"latest"` // последняя миграция в этой сборке
+	Error   string `json:"error,omitempty"`
+}
+
+// PoolStatsResponse — состояние пула соединений database/sql
+type PoolStatsResponse struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+}
+
+// Live godoc
+// @Summary      Проверка живости
+// @Description  Процесс запущен и отвечает. Зависимости не проверяются.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  LivenessResponse
+// @Router       /health/live [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, LivenessResponse{Status: healthOK})
+}
+
+// Ready godoc
+// @Summary      Проверка готовности
+// @Description  Пингует Postgres с таймаутом, сверяет версию схемы с последней миграцией сборки
+// @Description  и отдает статистику пула. 503 — если база недоступна, схема отстает или сервис завершается.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  ReadinessResponse
+// @Failure      503  {object}  ReadinessResponse
+// @Router       /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	response := ReadinessResponse{Status: healthOK}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.pingTimeout)
+	defer cancel()
+
+	logger := requestLogger(c, "Ready")
+	response.Database = h.checkDatabase(ctx, logger)
+	response.Migrations = h.checkMigrations(ctx, logger)
+
+	if sqlDB, err := h.db.DB(); err == nil {
+		stats := sqlDB.Stats()
+		response.Pool = PoolStatsResponse{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		}
+	}
+
+	switch {
+	case h.draining.Load():
+		response.Status = healthDraining
+	case response.Database.Status != healthOK || response.Migrations.Status != healthOK:
+		response.Status = healthUnavailable
+	}
+
+	status := http.StatusOK
+	if response.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// Проверка готовности доступна без авторизации, поэтому в ответ попадают только
+// фиксированные сообщения: текст ошибок драйвера может содержать хост, пользователя
+// и имя базы. Подробности пишутся в лог.
+const (
+	readyDatabaseUnavailable = "база данных недоступна"
+	readyVersionUnknown      = "не удалось определить версию схемы"
+	readySchemaBehind        = "схема базы отстает от сборки, нужна миграция"
+)
+
+func (h *HealthHandler) checkDatabase(ctx context.Context, logger *slog.Logger) DatabaseCheck {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		logger.Warn("Проверка готовности: нет соединения с базой", "error", err)
+		return DatabaseCheck{Status: healthUnavailable, Error: readyDatabaseUnavailable}
+	}
+
+	started := time.Now()
+	err = sqlDB.PingContext(ctx)
+	check := DatabaseCheck{Status: healthOK, LatencyMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		logger.Warn("Проверка готовности: база не отвечает", "error", err)
+		check.Status = healthUnavailable
+		check.Error = readyDatabaseUnavailable
+	}
+	return check
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context, logger *slog.Logger) MigrationsCheck {
+	check := MigrationsCheck{Status: healthOK}
+
+	latest, err := migrations.Latest()
+	if err != nil {
+		logger.Error("Проверка готовности: не удалось прочитать миграции сборки", "error", err)
+		return MigrationsCheck{Status: healthUnavailable, Error: readyVersionUnknown}
+	}
+	check.Latest = latest
+
+	current, err := migrations.Version(h.db.WithContext(ctx))
+	if err != nil {
+		logger.Warn("Проверка готовности: не удалось прочитать версию схемы", "error", err)
+		check.Status = healthUnavailable
+		check.Error = readyVersionUnknown
+		return check
+	}
+	check.Current = current
+
+	// Более новая схема допустима: ее могла накатить свежая реплика при выкладке
+	if current < latest {
+		check.Status = healthUnavailable
+		check.Error = readySchemaBehind
+	}
+	return check
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rest_service/internal/migrations"
+	"rest_service/internal/subscriptionService/sqlite"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newHealthRouter поднимает проверки здоровья поверх SQLite: пинг и
+// запрос версии схемы выполняются так же, как в Postgres
+func newHealthRouter(t *testing.T) (*gin.Engine, *HealthHandler, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "health.db"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	health := NewHealthHandler(db, time.Second)
+	r := gin.New()
+	r.GET("/health/live", health.Live)
+	r.GET("/health/ready", health.Ready)
+	return r, health, db
+}
+
+// setSchemaVersion создает schema_migrations и записывает в нее версию
+func setSchemaVersion(t *testing.T, db *gorm.DB, version int) {
+	t.Helper()
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+	if err == nil && version > 0 {
+		err = db.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", version, "test").Error
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealthLive(t *testing.T) {
+	r, _, _ := newHealthRouter(t)
+
+	w := doRequest(r, http.MethodGet, "/health/live", "", nil)
+	var response LivenessResponse
+	decodeJSON(t, w, &response)
+	if w.Code != http.StatusOK || response.Status != healthOK {
+		t.Errorf("статус %d, тело %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	latest, err := migrations.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		prepare        func(t *testing.T, db *gorm.DB, health *HealthHandler)
+		wantStatus     int
+		wantBody       string
+		wantMigrations string
+	}{
+		{
+			name:           "схема актуальна",
+			prepare:        func(t *testing.T, db *gorm.DB, _ *HealthHandler) { setSchemaVersion(t, db, latest) },
+			wantStatus:     http.StatusOK,
+			wantBody:       healthOK,
+			wantMigrations: healthOK,
+		},
+		{
+			name:           "миграции не применялись",
+			prepare:        func(*testing.T, *gorm.DB, *HealthHandler) {},
+			wantStatus:     http.StatusServiceUnavailable,
+			wantBody:       healthUnavailable,
+			wantMigrations: healthUnavailable,
+		},
+		{
+			name:           "схема отстает",
+			prepare:        func(t *testing.T, db *gorm.DB, _ *HealthHandler) { setSchemaVersion(t, db, latest-1) },
+			wantStatus:     http.StatusServiceUnavailable,
+			wantBody:       healthUnavailable,
+			wantMigrations: healthUnavailable,
+		},
+		{
+			name: "сервис завершается",
+			prepare: func(t *testing.T, db *gorm.DB, health *HealthHandler) {
+				setSchemaVersion(t, db, latest)
+				health.StartDraining()
+			},
+			wantStatus:     http.StatusServiceUnavailable,
+			wantBody:       healthDraining,
+			wantMigrations: healthOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, health, db := newHealthRouter(t)
+			tt.prepare(t, db, health)
+
+			w := doRequest(r, http.MethodGet, "/health/ready", "", nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("статус %d, ожидали %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			var response ReadinessResponse
+			decodeJSON(t, w, &response)
+			if response.Status != tt.wantBody {
+				t.Errorf("status = %q, ожидали %q", response.Status, tt.wantBody)
+			}
+			if response.Database.Status != healthOK {
+				t.Errorf("база недоступна: %+v", response.Database)
+			}
+			if response.Migrations.Status != tt.wantMigrations || response.Migrations.Latest != latest {
+				t.Errorf("migrations = %+v, ожидали статус %q и latest %d", response.Migrations, tt.wantMigrations, latest)
+			}
+			if response.Pool.MaxOpenConnections != 1 {
+				t.Errorf("статистика пула не заполнена: %+v", response.Pool)
+			}
+		})
+	}
+}
+
+// Готовность доступна без авторизации: текст ошибки драйвера (путь, хост,
+// пользователь) не должен попадать в ответ
+func TestHealthReadyHidesDriverErrors(t *testing.T) {
+	r, _, db := newHealthRouter(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	w := doRequest(r, http.MethodGet, "/health/ready", "", nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("статус %d, ожидали 503: %s", w.Code, w.Body.String())
+	}
+
+	var response ReadinessResponse
+	decodeJSON(t, w, &response)
+	if response.Database.Status != healthUnavailable || response.Database.Error != readyDatabaseUnavailable {
+		t.Errorf("database = %+v, ожидали фиксированное сообщение %q", response.Database, readyDatabaseUnavailable)
+	}
+	if response.Migrations.Error != readyVersionUnknown {
+		t.Errorf("migrations = %+v, ожидали фиксированное сообщение %q", response.Migrations, readyVersionUnknown)
+	}
+}
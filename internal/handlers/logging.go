@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"rest_service/internal/logging"
+	subscriptionService "rest_service/internal/subscriptionService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLogger возвращает логгер текущего запроса с именем хендлера
+func requestLogger(c *gin.Context, handler string) *slog.Logger {
+	return logging.FromContext(c.Request.Context()).With("handler", handler)
+}
+
+// logServiceError пишет ошибку сервиса в лог. Ошибки клиента (валидация,
+// не найдено, конфликт, отмена запроса) пишутся как info, таймауты — как warn,
+// остальные — как error.
+func logServiceError(logger *slog.Logger, msg string, err error) {
+	level := slog.LevelError
+	switch {
+	case isClientError(err):
+		level = slog.LevelInfo
+	case errors.Is(err, subscriptionService.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		level = slog.LevelWarn
+	}
+	logger.Log(context.Background(), level, msg, "error", err)
+}
+
+func isClientError(err error) bool {
+	return errors.Is(err, subscriptionService.ErrValidation) ||
+		errors.Is(err, subscriptionService.ErrNotFound) ||
+		errors.Is(err, subscriptionService.ErrConflict) ||
+		errors.Is(err, context.Canceled)
+}
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"log"
+	"cmp"
+	"io"
 	"net/http"
 	subscriptionService "rest_service/internal/subscriptionService"
 	"strconv"
@@ -17,71 +18,148 @@ func NewSubscriptionHadler(s subscriptionService.SubscriptionService) *Subscript
 	return &SubscriptionHadler{service: s}
 }
 
+// RegisterRoutes подключает маршруты подписок. Долгие операции (выгрузка,
+// импорт, пакеты, очистка корзины) идут в long, остальные — в api:
+// у групп разные таймауты.
+func (h *SubscriptionHadler) RegisterRoutes(api, long gin.IRoutes) {
+	api.GET("/subscriptions", h.ListSubscriptions)
+	api.POST("/subscriptions", h.CreateSubscription)
+	long.POST(batchRoutePath, h.BatchSubscriptions) // снаружи — /subscriptions:batch, см. CustomMethods
+	long.POST("/subscriptions/import", h.ImportSubscriptions)
+	api.GET("/subscriptions/:id", h.GetSubscriptionByID)
+	api.PUT("/subscriptions/:id", h.UpdateSubscriptionByID)
+	api.PATCH("/subscriptions/:id", h.PatchSubscriptionByID)
+	api.DELETE("/subscriptions/:id", h.DeleteSubcriptionByID)
+	api.GET("/subscriptions/amountSubscriptions", h.GetAmountOfsubscriptions)
+	long.GET("/subscriptions/amountSubscriptions/export", h.ExportAmountOfsubscriptions)
+	long.GET("/subscriptions/export", h.ExportSubscriptions)
+	api.GET("/subscriptions/deleted", h.ListDeletedSubscriptions)
+	long.DELETE("/subscriptions/deleted", h.PurgeDeletedSubscriptions)
+	api.POST("/subscriptions/:id/restore", h.RestoreSubscriptionByID)
+}
+
 // ListSubscriptions godoc
 // @Summary      Получить список подписок
-// @Description  Возвращает список всех подписок
+// @Description  Возвращает страницу подписок с учетом фильтров и сортировки.
+// @Description  С pagination=cursor (или с параметром cursor) список отдается по курсору
+// @Description  в порядке (created_at, id): ссылки на соседние страницы приходят в meta.next_cursor/prev_cursor.
 // @Tags         subscriptions
 // @Produce      json
-// @Success      200  {array}  subscriptionService.Subscription
-// @Failure      500  {object}  map[string]string
+// @Param        pagination           query     string  false  "Режим пагинации"  Enums(page, cursor)  default(page)
+// @Param        cursor               query     string  false  "Курсор из meta.next_cursor или meta.prev_cursor"
+// @Param        page                 query     int     false  "Номер страницы"  default(1)
+// @Param        limit                query     int     false  "Размер страницы (не больше 100)"  default(10)
+// @Param        user_id              query     string  false  "ID пользователя"
+// @Param        service_name         query     string  false  "Название сервиса (точное совпадение)"
+// @Param        service_name_prefix  query     string  false  "Начало названия сервиса"
+// @Param        min_price            query     int     false  "Минимальная цена"
+// @Param        max_price            query     int     false  "Максимальная цена"
+// @Param        active_at            query     string  false  "Подписка действует в этот день или хотя бы день этого месяца (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        start_from           query     string  false  "Начало не раньше (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        start_to             query     string  false  "Начало не позже (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        end_from             query     string  false  "Окончание не раньше (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        end_to               query     string  false  "Окончание не позже (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        sort                 query     string  false  "Сортировка, например -price,start_date"
+// @Success      200                  {object}  subscriptionService.PaginatedResponse
+// @Failure      400                  {object}  ProblemDetails
+// @Failure      500                  {object}  ProblemDetails
+// @Failure      504                  {object}  ProblemDetails
 // @Router       /subscriptions [get]
 func (h *SubscriptionHadler) ListSubscriptions(c *gin.Context) {
-	log.Println("[ListSubscriptions] Вход в хендлер")
+	logger := requestLogger(c, "ListSubscriptions")
+	logger.Debug("Вход в хендлер")
 
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		log.Println("Неверные параметры запроса")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный номер страницы"})
+	page, limit, ok := parsePagination(c)
+	if !ok {
 		return
 	}
 
-	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	if err != nil || page < 1 || limit > 100 {
-		log.Println("Неверное количество элементов")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный количество элементов"})
-		return
-	}
+	params := listParamsFromQuery(c)
+	params.Page = page
+	params.Limit = limit
+	params.Cursor = c.Query("cursor")
+	params.CursorMode = params.Cursor != "" || c.Query("pagination") == "cursor"
 
-	paginatedResponse, err := h.service.ListSubscriptions(page, limit)
+	paginatedResponse, err := h.service.ListSubscriptions(c.Request.Context(), params)
 	if err != nil {
-		log.Printf("[ListSubscriptions] Ошибка получения подписок: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось получить список подписок"})
+		logServiceError(logger, "Ошибка получения подписок", err)
+		writeError(c, err)
 		return
 	}
 
-	log.Printf("[ListSubscriptions] Подписок получено: %d\n", len(paginatedResponse.Data))
+	logger.Info("Подписки получены", "count", len(paginatedResponse.Data))
 	c.JSON(http.StatusOK, paginatedResponse)
 }
 
+// listParamsFromQuery читает из запроса фильтры и сортировку списка подписок
+func listParamsFromQuery(c *gin.Context) subscriptionService.RequestListParameters {
+	return subscriptionService.RequestListParameters{
+		UserID:            c.Query("user_id"),
+		ServiceName:       c.Query("service_name"),
+		ServiceNamePrefix: c.Query("service_name_prefix"),
+		MinPrice:          c.Query("min_price"),
+		MaxPrice:          c.Query("max_price"),
+		ActiveAt:          c.Query("active_at"),
+		StartFrom:         c.Query("start_from"),
+		StartTo:           c.Query("start_to"),
+		EndFrom:           c.Query("end_from"),
+		EndTo:             c.Query("end_to"),
+		Sort:              c.Query("sort"),
+	}
+}
+
+// parsePagination читает page и limit из запроса; при ошибке сам отвечает клиенту
+func parsePagination(c *gin.Context) (page, limit int, ok bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		requestLogger(c, "parsePagination").Info("Некорректный номер страницы", "page", c.Query("page"))
+		writeProblem(c, http.StatusBadRequest, codeInvalidPagination, "Некорректный номер страницы")
+		return 0, 0, false
+	}
+
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		requestLogger(c, "parsePagination").Info("Некорректное количество элементов", "limit", c.Query("limit"))
+		writeProblem(c, http.StatusBadRequest, codeInvalidPagination, "Некорректный количество элементов")
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
 // CreateSubscription godoc
 // @Summary      Создать новую подписку
-// @Description  Создает подписку с переданными параметрами
+// @Description  Создает подписку с переданными параметрами.
+// @Description  Цена больше нуля, end_date не раньше start_date, currency — из списка поддерживаемых (по умолчанию RUB).
+// @Description  Даты — YYYY-MM-DD (или дата-время RFC 3339), YYYY-MM или MM-YYYY; месяц без дня в end_date означает его последний день.
+// @Description  При ошибках валидации (code=validation_failed) все некорректные поля перечислены в errors.
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        subscription  body      subscriptionService.RequestBody  true  "Данные подписки"
 // @Success      200           {object}  subscriptionService.Subscription
-// @Failure      400           {object}  map[string]string
-// @Failure      500           {object}  map[string]string
+// @Failure      400           {object}  ProblemDetails
+// @Failure      500           {object}  ProblemDetails
+// @Failure      504           {object}  ProblemDetails
 // @Router       /subscriptions [post]
 func (h *SubscriptionHadler) CreateSubscription(c *gin.Context) {
-	log.Println("[CreateSubscription] Вход в хендлер")
+	logger := requestLogger(c, "CreateSubscription")
+	logger.Debug("Вход в хендлер")
 
 	var req subscriptionService.RequestBody
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[CreateSubscription] Ошибка привязки JSON: %v\n", err)
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if !bindRequestBody(c, logger, &req) {
 		return
 	}
 
-	sub, err := h.service.CreateSubscriptions(req)
+	sub, err := h.service.CreateSubscriptions(c.Request.Context(), req)
 	if err != nil {
-		log.Printf("[CreateSubscription] Ошибка создания подписки: %v\n", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		logServiceError(logger, "Ошибка создания подписки", err)
+		writeError(c, err)
 		return
 	}
 
-	log.Printf("[CreateSubscription] Подписка создана: %+v\n", sub)
+	logger.Info("Подписка создана", "subscription_id", sub.ID)
+	c.Header("ETag", etag(sub.Version))
 	c.JSON(http.StatusOK, sub)
 }
 
@@ -92,57 +170,131 @@ func (h *SubscriptionHadler) CreateSubscription(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      string  true  "ID подписки"
 // @Success      200  {object}  subscriptionService.Subscription
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  ProblemDetails
+// @Failure      404  {object}  ProblemDetails
+// @Failure      500  {object}  ProblemDetails
+// @Failure      504  {object}  ProblemDetails
 // @Router       /subscriptions/{id} [get]
 func (h *SubscriptionHadler) GetSubscriptionByID(c *gin.Context) {
 	idstr := c.Param("id")
-	log.Printf("[GetSubscriptionByID] Поиск подписки по ID: %s\n", idstr)
+	logger := requestLogger(c, "GetSubscriptionByID").With("subscription_id", idstr)
+	logger.Debug("Поиск подписки по ID")
 
-	sub, err := h.service.GetSubscriptionByID(idstr)
+	sub, err := h.service.GetSubscriptionByID(c.Request.Context(), idstr)
 	if err != nil {
-		log.Printf("[GetSubscriptionByID] Ошибка: %v\n", err)
-		c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		logServiceError(logger, "Ошибка получения подписки", err)
+		writeError(c, err)
 		return
 	}
 
-	log.Printf("[GetSubscriptionByID] Подписка найдена: %+v\n", sub)
+	logger.Debug("Подписка найдена", "version", sub.Version)
+	c.Header("ETag", etag(sub.Version))
 	c.JSON(http.StatusOK, sub)
 }
 
 // UpdateSubscriptionByID godoc
 // @Summary      Обновить подписку по ID
-// @Description  Обновляет данные подписки с указанным ID
+// @Description  Обновляет данные подписки с указанным ID.
+// @Description  Если передан If-Match, обновление выполняется только для указанной версии (ETag).
+// @Description  Тело проверяется так же, как при создании; ошибки полей возвращаются в errors.
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
-// @Param        id            path      string                           true  "ID подписки"
-// @Param        subscription  body      subscriptionService.RequestBody  true  "Обновленные данные подписки"
+// @Param        id            path      string                           true   "ID подписки"
+// @Param        If-Match      header    string                           false  "ETag подписки, полученный при чтении"
+// @Param        subscription  body      subscriptionService.RequestBody  true   "Обновленные данные подписки"
 // @Success      200           {object}  subscriptionService.Subscription
-// @Failure      400           {object}  map[string]string
-// @Failure      500           {object}  map[string]string
+// @Failure      400           {object}  ProblemDetails
+// @Failure      404           {object}  ProblemDetails
+// @Failure      412           {object}  ProblemDetails
+// @Failure      500           {object}  ProblemDetails
+// @Failure      504           {object}  ProblemDetails
 // @Router       /subscriptions/{id} [put]
 func (h *SubscriptionHadler) UpdateSubscriptionByID(c *gin.Context) {
-	log.Println("[UpdateSubscriptionByID] Вход в хендлер")
+	logger := requestLogger(c, "UpdateSubscriptionByID")
+	logger.Debug("Вход в хендлер")
 
 	var req subscriptionService.RequestBody
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[UpdateSubscriptionByID] Ошибка привязки JSON: %v\n", err)
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if !bindRequestBody(c, logger, &req) {
+		return
+	}
+
+	expectedVersions, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		logger.Info("Некорректный If-Match", "error", err)
+		writeProblem(c, http.StatusBadRequest, codeInvalidIfMatch, err.Error())
 		return
 	}
 
 	idstr := c.Param("id")
-	updatedSub, err := h.service.UpdateSubcriptionByID(req, idstr)
+	updatedSub, err := h.service.UpdateSubcriptionByID(c.Request.Context(), req, idstr, expectedVersions)
 	if err != nil {
-		log.Printf("[UpdateSubscriptionByID] Ошибка обновления подписки ID=%s: %v\n", idstr, err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		logServiceError(logger.With("subscription_id", idstr), "Ошибка обновления подписки", err)
+		writeError(c, err)
 		return
 	}
 
-	log.Printf("[UpdateSubscriptionByID] Подписка обновлена: %+v\n", updatedSub)
+	logger.Info("Подписка обновлена", "subscription_id", updatedSub.ID, "version", updatedSub.Version)
+	c.Header("ETag", etag(updatedSub.Version))
 	c.JSON(http.StatusOK, updatedSub)
 }
 
+// PatchSubscriptionByID godoc
+// @Summary      Частично обновить подписку по ID
+// @Description  Применяет JSON Merge Patch (RFC 7396) к подписке с указанным ID.
+// @Description  null в патче очищает поле (например, end_date).
+// @Tags         subscriptions
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id        path      string  true   "ID подписки"
+// @Param        If-Match  header    string  false  "ETag подписки, полученный при чтении"
+// @Param        patch     body      object  true   "Merge-patch документ с изменяемыми полями"
+// @Success      200       {object}  subscriptionService.Subscription
+// @Failure      400       {object}  ProblemDetails
+// @Failure      404       {object}  ProblemDetails
+// @Failure      412       {object}  ProblemDetails
+// @Failure      415       {object}  ProblemDetails
+// @Failure      500       {object}  ProblemDetails
+// @Failure      504       {object}  ProblemDetails
+// @Router       /subscriptions/{id} [patch]
+func (h *SubscriptionHadler) PatchSubscriptionByID(c *gin.Context) {
+	logger := requestLogger(c, "PatchSubscriptionByID")
+	logger.Debug("Вход в хендлер")
+
+	contentType := c.ContentType()
+	if contentType != "application/merge-patch+json" && contentType != "application/json" {
+		logger.Info("Неподдерживаемый Content-Type", "content_type", contentType)
+		writeProblem(c, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "ожидается application/merge-patch+json")
+		return
+	}
+
+	expectedVersions, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		logger.Info("Некорректный If-Match", "error", err)
+		writeProblem(c, http.StatusBadRequest, codeInvalidIfMatch, err.Error())
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		logger.Warn("Ошибка чтения тела запроса", "error", err)
+		writeProblem(c, http.StatusBadRequest, codeInvalidRequestBody, err.Error())
+		return
+	}
+
+	idstr := c.Param("id")
+	patchedSub, err := h.service.PatchSubcriptionByID(c.Request.Context(), patch, idstr, expectedVersions)
+	if err != nil {
+		logServiceError(logger.With("subscription_id", idstr), "Ошибка обновления подписки", err)
+		writeError(c, err)
+		return
+	}
+
+	logger.Info("Подписка обновлена", "subscription_id", patchedSub.ID, "version", patchedSub.Version)
+	c.Header("ETag", etag(patchedSub.Version))
+	c.JSON(http.StatusOK, patchedSub)
+}
+
 // DeleteSubcriptionByID godoc
 // @Summary      Удалить подписку по ID
 // @Description  Удаляет подписку с указанным ID
@@ -150,54 +302,492 @@ func (h *SubscriptionHadler) UpdateSubscriptionByID(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      string  true  "ID подписки"
 // @Success      204  {string}  string  "No Content"
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  ProblemDetails
+// @Failure      404  {object}  ProblemDetails
+// @Failure      500  {object}  ProblemDetails
+// @Failure      504  {object}  ProblemDetails
 // @Router       /subscriptions/{id} [delete]
 func (h *SubscriptionHadler) DeleteSubcriptionByID(c *gin.Context) {
 	idstr := c.Param("id")
-	log.Printf("[DeleteSubcriptionByID] Удаление подписки ID=%s\n", idstr)
+	logger := requestLogger(c, "DeleteSubcriptionByID").With("subscription_id", idstr)
+	logger.Debug("Удаление подписки")
 
-	err := h.service.DeleteSubcriptionByID(idstr)
+	err := h.service.DeleteSubcriptionByID(c.Request.Context(), idstr)
 	if err != nil {
-		log.Printf("[DeleteSubcriptionByID] Ошибка удаления: %v\n", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		logServiceError(logger, "Ошибка удаления подписки", err)
+		writeError(c, err)
 		return
 	}
 
-	log.Printf("[DeleteSubcriptionByID] Подписка удалена ID=%s\n", idstr)
+	logger.Info("Подписка удалена")
 	c.JSON(http.StatusNoContent, "")
 }
 
 // GetAmountOfsubscriptions godoc
 // @Summary      Получить сумму подписок по фильтрам
-// @Description  Возвращает общую сумму подписок за указанный период с учетом фильтров
+// @Description  Возвращает общую сумму подписок за указанный период с учетом фильтров.
+// @Description  Подписка списывается в день начала и далее каждый месяц в тот же день (31-го — в последний день короткого месяца);
+// @Description  в сумму входит каждое списание внутри периода.
+// @Description  С mode=prorated за каждый календарный месяц берется доля цены по дням действия подписки
+// @Description  (price × дни / дней в месяце, округление до целого); подписка, отмененная 2-го числа, стоит 2/31 цены за месяц.
+// @Description  Цены в разных валютах не складываются: сумма считается только по подпискам в валюте currency (по умолчанию RUB).
+// @Description  С параметром group_by дополнительно возвращает суммы по группам и помесячную динамику.
 // @Tags         subscriptions
 // @Produce      json
-// @Param        start_date    query     string  false  "Дата начала (YYYY-MM-DD)"
-// @Param        end_date      query     string  false  "Дата окончания (YYYY-MM-DD)"
+// @Param        start_date    query     string  false  "Дата начала (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        end_date      query     string  false  "Дата окончания (YYYY-MM-DD, YYYY-MM или MM-YYYY; месяц — включая последний день)"
 // @Param        user_id       query     string  false  "ID пользователя"
 // @Param        name_service  query     string  false  "Название сервиса"
-// @Success      200           {object} map[string]int
-// @Failure      500           {object}  map[string]string
+// @Param        currency      query     string  false  "Валюта (ISO 4217): суммируются только подписки в ней"  default(RUB)
+// @Param        group_by      query     string  false  "Группировка"  Enums(service_name, user_id, month)
+// @Param        mode          query     string  false  "Режим расчета"  Enums(monthly, prorated)  default(monthly)
+// @Success      200           {object}  subscriptionService.CostBreakdown
+// @Failure      400           {object}  ProblemDetails
+// @Failure      500           {object}  ProblemDetails
+// @Failure      504           {object}  ProblemDetails
 // @Router       /subscriptions/amountSubscriptions [get]
 func (h *SubscriptionHadler) GetAmountOfsubscriptions(c *gin.Context) {
-	log.Println("[GetAmountOfsubscriptions] Вход в хендлер")
+	logger := requestLogger(c, "GetAmountOfsubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	params := subscriptionService.RequestParametersСalculatingSum{
+		StartDate:   c.Query("start_date"),
+		EndDate:     c.Query("end_date"),
+		UserID:      c.Query("user_id"),
+		ServiceName: c.Query("name_service"),
+		Currency:    c.Query("currency"),
+		Mode:        c.Query("mode"),
+	}
+
+	logger.Debug("Параметры расчета суммы",
+		"start_date", params.StartDate, "end_date", params.EndDate,
+		"user_id", params.UserID, "service_name", params.ServiceName, "currency", params.Currency, "mode", params.Mode)
+
+	if groupBy := c.Query("group_by"); groupBy != "" {
+		breakdown, err := h.service.GetCostBreakdown(c.Request.Context(), params, groupBy)
+		if err != nil {
+			logServiceError(logger, "Ошибка вычисления суммы по группам", err)
+			writeError(c, err)
+			return
+		}
+
+		logger.Info("Сумма подписок посчитана", "total_price", breakdown.TotalPrice, "groups", len(breakdown.Groups))
+		c.JSON(http.StatusOK, breakdown)
+		return
+	}
+
+	total, err := h.service.GetAmountOfsubscriptions(c.Request.Context(), params)
+	if err != nil {
+		logServiceError(logger, "Ошибка вычисления суммы", err)
+		writeError(c, err)
+		return
+	}
+
+	currency := cmp.Or(params.Currency, subscriptionService.DefaultCurrency)
+	logger.Info("Сумма подписок посчитана", "total_price", total, "currency", currency)
+	c.JSON(http.StatusOK, subscriptionService.CostBreakdown{TotalPrice: total, Currency: currency})
+}
+
+// ListDeletedSubscriptions godoc
+// @Summary      Получить список удаленных подписок
+// @Description  Возвращает мягко удаленные подписки (корзину), сначала недавно удаленные
+// @Tags         trash
+// @Produce      json
+// @Param        page   query     int  false  "Номер страницы"  default(1)
+// @Param        limit  query     int  false  "Размер страницы (не больше 100)"  default(10)
+// @Success      200    {object}  subscriptionService.DeletedPaginatedResponse
+// @Failure      400    {object}  ProblemDetails
+// @Failure      500    {object}  ProblemDetails
+// @Failure      504    {object}  ProblemDetails
+// @Router       /subscriptions/deleted [get]
+func (h *SubscriptionHadler) ListDeletedSubscriptions(c *gin.Context) {
+	logger := requestLogger(c, "ListDeletedSubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.service.ListDeletedSubscriptions(c.Request.Context(), page, limit)
+	if err != nil {
+		logServiceError(logger, "Ошибка получения удаленных подписок", err)
+		writeError(c, err)
+		return
+	}
+
+	logger.Info("Удаленные подписки получены", "count", len(response.Data))
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreSubscriptionByID godoc
+// @Summary      Восстановить удаленную подписку
+// @Description  Снимает с подписки пометку об удалении
+// @Tags         trash
+// @Produce      json
+// @Param        id   path      string  true  "ID подписки"
+// @Success      200  {object}  subscriptionService.Subscription
+// @Failure      400  {object}  ProblemDetails
+// @Failure      404  {object}  ProblemDetails
+// @Failure      409  {object}  ProblemDetails
+// @Failure      500  {object}  ProblemDetails
+// @Failure      504  {object}  ProblemDetails
+// @Router       /subscriptions/{id}/restore [post]
+func (h *SubscriptionHadler) RestoreSubscriptionByID(c *gin.Context) {
+	idstr := c.Param("id")
+	logger := requestLogger(c, "RestoreSubscriptionByID").With("subscription_id", idstr)
+	logger.Debug("Восстановление подписки")
+
+	sub, err := h.service.RestoreSubscriptionByID(c.Request.Context(), idstr)
+	if err != nil {
+		logServiceError(logger, "Ошибка восстановления подписки", err)
+		writeError(c, err)
+		return
+	}
+
+	logger.Info("Подписка восстановлена", "version", sub.Version)
+	c.Header("ETag", etag(sub.Version))
+	c.JSON(http.StatusOK, sub)
+}
+
+// PurgeDeletedSubscriptions godoc
+// @Summary      Очистить корзину
+// @Description  Окончательно удаляет подписки, которые находятся в корзине дольше срока хранения
+// @Description  (SOFT_DELETE_RETENTION_DAYS)
+// @Tags         trash
+// @Produce      json
+// @Success      200  {object}  subscriptionService.PurgeResult
+// @Failure      500  {object}  ProblemDetails
+// @Failure      504  {object}  ProblemDetails
+// @Router       /subscriptions/deleted [delete]
+func (h *SubscriptionHadler) PurgeDeletedSubscriptions(c *gin.Context) {
+	logger := requestLogger(c, "PurgeDeletedSubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	result, err := h.service.PurgeDeletedSubscriptions(c.Request.Context())
+	if err != nil {
+		logServiceError(logger, "Ошибка очистки корзины", err)
+		writeError(c, err)
+		return
+	}
+
+	logger.Info("Корзина очищена", "purged", result.Purged)
+	c.JSON(http.StatusOK, result)
+}
+
+// BatchItemResponse — результат одной операции пакета
+type BatchItemResponse struct {
+	subscriptionService.BatchItemResult
+	Error *ProblemDetails `json:"error,omitempty"`
+}
+
+// BatchResponse — результат выполнения пакета операций
+type BatchResponse struct {
+	Mode      string              `json:"mode"`
+	Committed bool                `json:"committed"`
+	Results   []BatchItemResponse `json:"results"`
+}
+
+// BatchSubscriptions godoc
+// @Summary      Пакетное создание, обновление и удаление подписок
+// @Description  Выполняет массив операций create/update/delete с теми же проверками, что и одиночные запросы.
+// @Description  mode=atomic (по умолчанию) — одна транзакция: при первой ошибке все изменения отменяются (422).
+// @Description  mode=best_effort — операции выполняются независимо; если часть не удалась, ответ 207.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      subscriptionService.BatchRequest  true  "Операции"
+// @Success      200    {object}  BatchResponse
+// @Success      207    {object}  BatchResponse
+// @Failure      400    {object}  ProblemDetails
+// @Failure      422    {object}  BatchResponse
+// @Failure      500    {object}  ProblemDetails
+// @Failure      504    {object}  ProblemDetails
+// @Router       /subscriptions:batch [post]
+func (h *SubscriptionHadler) BatchSubscriptions(c *gin.Context) {
+	logger := requestLogger(c, "BatchSubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	var req subscriptionService.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Info("Ошибка привязки JSON", "error", err)
+		writeProblem(c, http.StatusBadRequest, codeInvalidRequestBody, err.Error())
+		return
+	}
+
+	result, err := h.service.ExecuteBatch(c.Request.Context(), req)
+	if err != nil {
+		logServiceError(logger, "Ошибка выполнения пакета", err)
+		writeError(c, err)
+		return
+	}
+
+	response := BatchResponse{Mode: result.Mode, Committed: result.Committed, Results: make([]BatchItemResponse, 0, len(result.Results))}
+	failed := 0
+	for _, item := range result.Results {
+		itemResponse := BatchItemResponse{BatchItemResult: item}
+		if item.Err != nil {
+			problem := problemFor(c, item.Err)
+			itemResponse.Error = &problem
+			failed++
+		}
+		response.Results = append(response.Results, itemResponse)
+	}
+
+	status := http.StatusOK
+	switch {
+	case !result.Committed:
+		status = http.StatusUnprocessableEntity
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+
+	logger.Info("Пакет выполнен", "mode", result.Mode, "operations", len(result.Results), "failed", failed, "committed", result.Committed)
+	c.JSON(status, response)
+}
+
+// maxImportSize ограничивает размер загружаемого CSV-файла
+const maxImportSize = 10 << 20
+
+// ImportSubscriptions godoc
+// @Summary      Импорт подписок из CSV
+// @Description  Принимает CSV с колонками service_name, price, user_id, start_date (YYYY-MM-DD, YYYY-MM или MM-YYYY), end_date и currency (обе необязательны)
+// @Description  как файл multipart/form-data (поле file) или как тело text/csv.
+// @Description  Если хотя бы одна строка с ошибкой, ничего не сохраняется (422) и возвращается список ошибок по строкам.
+// @Description  С dry_run=true файл только проверяется.
+// @Tags         subscriptions
+// @Accept       text/csv,multipart/form-data
+// @Produce      json
+// @Param        dry_run  query     bool    false  "Только проверить файл, ничего не сохраняя"
+// @Param        file     formData  file    false  "CSV-файл"
+// @Success      200      {object}  subscriptionService.ImportResult
+// @Failure      400      {object}  ProblemDetails
+// @Failure      422      {object}  subscriptionService.ImportResult
+// @Failure      500      {object}  ProblemDetails
+// @Failure      504      {object}  ProblemDetails
+// @Router       /subscriptions/import [post]
+func (h *SubscriptionHadler) ImportSubscriptions(c *gin.Context) {
+	logger := requestLogger(c, "ImportSubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		writeProblem(c, http.StatusBadRequest, codeInvalidRequestBody, "dry_run должен быть true или false")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var input io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			logger.Info("Не удалось получить файл", "error", err)
+			writeProblem(c, http.StatusBadRequest, codeInvalidRequestBody, "ожидается CSV-файл в поле file")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			logger.Warn("Не удалось открыть файл", "error", err)
+			writeProblem(c, http.StatusBadRequest, codeInvalidRequestBody, "не удалось прочитать файл")
+			return
+		}
+		defer file.Close()
+		input = file
+	}
+
+	result, err := h.service.ImportSubscriptions(c.Request.Context(), input, dryRun)
+	if err != nil {
+		logServiceError(logger, "Ошибка импорта", err)
+		writeError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	logger.Info("Импорт обработан", "dry_run", dryRun, "rows", result.TotalRows, "invalid_rows", len(result.Errors), "imported", result.Imported)
+	c.JSON(status, result)
+}
+
+// negotiateExportFormat выбирает формат выгрузки по Accept; при неудаче сам отвечает 406
+func negotiateExportFormat(c *gin.Context) (string, bool) {
+	switch c.NegotiateFormat(mimeCSV, mimeNDJSON, "application/ndjson") {
+	case mimeCSV:
+		return mimeCSV, true
+	case mimeNDJSON, "application/ndjson":
+		return mimeNDJSON, true
+	default:
+		writeProblem(c, http.StatusNotAcceptable, codeNotAcceptable, "поддерживаются text/csv и application/x-ndjson")
+		return "", false
+	}
+}
+
+// startExport отправляет заголовки ответа с выгрузкой
+func startExport(c *gin.Context, format, name string) {
+	extension := ".csv"
+	if format == mimeNDJSON {
+		extension = ".ndjson"
+	}
+	c.Header("Content-Type", format+"; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+name+extension+`"`)
+	c.Status(http.StatusOK)
+}
+
+// ExportSubscriptions godoc
+// @Summary      Выгрузить подписки
+// @Description  Потоково выгружает все подписки, подходящие под фильтры списка, в CSV или NDJSON (по заголовку Accept).
+// @Tags         export
+// @Produce      text/csv,application/x-ndjson
+// @Param        user_id              query     string  false  "ID пользователя"
+// @Param        service_name         query     string  false  "Название сервиса (точное совпадение)"
+// @Param        service_name_prefix  query     string  false  "Начало названия сервиса"
+// @Param        min_price            query     int     false  "Минимальная цена"
+// @Param        max_price            query     int     false  "Максимальная цена"
+// @Param        active_at            query     string  false  "Подписка действует в этот день или хотя бы день этого месяца (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        start_from           query     string  false  "Начало не раньше (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        start_to             query     string  false  "Начало не позже (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        end_from             query     string  false  "Окончание не раньше (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        end_to               query     string  false  "Окончание не позже (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        sort                 query     string  false  "Сортировка, например -price,start_date"
+// @Success      200                  {string}  string  "Строки CSV или NDJSON"
+// @Failure      400                  {object}  ProblemDetails
+// @Failure      406                  {object}  ProblemDetails
+// @Failure      500                  {object}  ProblemDetails
+// @Failure      504                  {object}  ProblemDetails
+// @Router       /subscriptions/export [get]
+func (h *SubscriptionHadler) ExportSubscriptions(c *gin.Context) {
+	logger := requestLogger(c, "ExportSubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	format, ok := negotiateExportFormat(c)
+	if !ok {
+		return
+	}
+
+	var (
+		writer recordWriter
+		count  int
+	)
+	err := h.service.ExportSubscriptions(c.Request.Context(), listParamsFromQuery(c), func(s subscriptionService.Subscription) error {
+		// Заголовки отправляем при первой записи, чтобы ошибку фильтров можно было вернуть как 400
+		if writer == nil {
+			startExport(c, format, "subscriptions")
+			var err error
+			if writer, err = newRecordWriter(format, c.Writer, subscriptionCSVHeader); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.write(subscriptionCSVRow(s), s); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := writer.flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	if err != nil && writer == nil {
+		logServiceError(logger, "Ошибка выгрузки", err)
+		writeError(c, err)
+		return
+	}
+	if err != nil {
+		// Часть данных уже отправлена, статус поменять нельзя — рвем соединение,
+		// чтобы клиент не принял обрезанный файл за целый
+		logger.Warn("Выгрузка прервана", "rows", count, "error", err)
+		abortStream(c)
+		return
+	}
+
+	if writer == nil {
+		// Ни одной подписки: отдаем пустой файл (для CSV — только заголовок)
+		startExport(c, format, "subscriptions")
+		if writer, err = newRecordWriter(format, c.Writer, subscriptionCSVHeader); err != nil {
+			logger.Warn("Ошибка записи выгрузки", "error", err)
+			return
+		}
+	}
+	if err := writer.flush(); err != nil {
+		logger.Warn("Ошибка записи выгрузки", "error", err)
+		return
+	}
+
+	logger.Info("Подписки выгружены", "format", format, "rows", count)
+}
+
+// ExportAmountOfsubscriptions godoc
+// @Summary      Выгрузить суммы подписок по группам и месяцам
+// @Description  Выгружает помесячные суммы подписок (как group_by у /subscriptions/amountSubscriptions)
+// @Description  в CSV или NDJSON: одна строка на пару «группа, месяц».
+// @Tags         export
+// @Produce      text/csv,application/x-ndjson
+// @Param        start_date    query     string  true   "Дата начала (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        end_date      query     string  true   "Дата окончания (YYYY-MM-DD, YYYY-MM или MM-YYYY)"
+// @Param        user_id       query     string  false  "ID пользователя"
+// @Param        name_service  query     string  false  "Название сервиса"
+// @Param        currency      query     string  false  "Валюта (ISO 4217): суммируются только подписки в ней"  default(RUB)
+// @Param        group_by      query     string  false  "Группировка"  Enums(service_name, user_id, month)  default(month)
+// @Param        mode          query     string  false  "Режим расчета"  Enums(monthly, prorated)  default(monthly)
+// @Success      200           {string}  string  "Строки CSV или NDJSON"
+// @Failure      400           {object}  ProblemDetails
+// @Failure      406           {object}  ProblemDetails
+// @Failure      500           {object}  ProblemDetails
+// @Failure      504           {object}  ProblemDetails
+// @Router       /subscriptions/amountSubscriptions/export [get]
+func (h *SubscriptionHadler) ExportAmountOfsubscriptions(c *gin.Context) {
+	logger := requestLogger(c, "ExportAmountOfsubscriptions")
+	logger.Debug("Вход в хендлер")
+
+	format, ok := negotiateExportFormat(c)
+	if !ok {
+		return
+	}
 
 	params := subscriptionService.RequestParametersСalculatingSum{
 		StartDate:   c.Query("start_date"),
 		EndDate:     c.Query("end_date"),
 		UserID:      c.Query("user_id"),
 		ServiceName: c.Query("name_service"),
+		Currency:    c.Query("currency"),
+		Mode:        c.Query("mode"),
 	}
 
-	log.Printf("[GetAmountOfsubscriptions] Параметры: %+v\n", params)
+	breakdown, err := h.service.GetCostBreakdown(c.Request.Context(), params, c.DefaultQuery("group_by", subscriptionService.GroupByMonth))
+	if err != nil {
+		logServiceError(logger, "Ошибка вычисления суммы по группам", err)
+		writeError(c, err)
+		return
+	}
 
-	total, err := h.service.GetAmountOfsubscriptions(params)
+	startExport(c, format, "subscriptions_amount")
+	writer, err := newRecordWriter(format, c.Writer, costCSVHeader)
 	if err != nil {
-		log.Printf("[GetAmountOfsubscriptions] Ошибка вычисления суммы: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Warn("Ошибка записи выгрузки", "error", err)
+		return
+	}
+
+	for _, group := range breakdown.Groups {
+		for _, month := range group.Series {
+			row := costExportRow{Group: group.Key, Month: month.Month, Currency: breakdown.Currency, TotalPrice: month.TotalPrice}
+			if err := writer.write(row.csvRow(), row); err != nil {
+				logger.Warn("Ошибка записи выгрузки", "error", err)
+				return
+			}
+		}
+	}
+	if err := writer.flush(); err != nil {
+		logger.Warn("Ошибка записи выгрузки", "error", err)
 		return
 	}
 
-	log.Printf("[GetAmountOfsubscriptions] Сумма: %v\n", total)
-	c.JSON(http.StatusOK, gin.H{"total_price": total})
+	logger.Info("Суммы выгружены", "format", format, "groups", len(breakdown.Groups))
 }
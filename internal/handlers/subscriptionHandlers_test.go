@@ -0,0 +1,759 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"rest_service/internal/logging"
+	"rest_service/internal/metrics"
+	subscriptionService "rest_service/internal/subscriptionService"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newTestRouter собирает роутер с теми же маршрутами и middleware, что и main,
+// поверх сервиса с репозиторием в памяти. База нужна только /health/ready,
+// поэтому здесь его нет: проверки здоровья — в health_test.go.
+func newTestRouter(t *testing.T, requestTimeout, longRequestTimeout time.Duration) http.Handler {
+	t.Helper()
+	return newTestRouterWithRepo(t, subscriptionService.NewMemoryRepository(), requestTimeout, longRequestTimeout)
+}
+
+// newTestRouterWithRepo — то же, что newTestRouter, поверх заданного репозитория
+func newTestRouterWithRepo(t *testing.T, repo subscriptionService.SubscriptionRepository, requestTimeout, longRequestTimeout time.Duration) http.Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	service := subscriptionService.NewSubscriptionService(repo)
+	appMetrics := metrics.New()
+	appMetrics.RegisterBusiness(service)
+
+	r := gin.New()
+	r.Use(StreamAbort())
+	r.Use(gin.Recovery())
+	r.Use(logging.GinMiddleware(slog.New(slog.NewJSONHandler(io.Discard, nil))))
+	r.Use(appMetrics.GinMiddleware())
+
+	r.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "pong"})
+	})
+	r.GET("/metrics", gin.WrapH(appMetrics.Handler()))
+
+	api := r.Group("", Timeout(requestTimeout))
+	long := r.Group("", Timeout(longRequestTimeout))
+	NewSubscriptionHadler(service).RegisterRoutes(api, long)
+	return CustomMethods(r)
+}
+
+func doRequest(r http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("тело ответа не JSON: %v\n%s", err, w.Body.String())
+	}
+}
+
+// requireProblem проверяет, что ответ — problem+json с нужными статусом и кодом
+func requireProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) ProblemDetails {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("статус %d, ожидали %d: %s", w.Code, status, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Errorf("Content-Type = %q, ожидали application/problem+json", ct)
+	}
+
+	var problem ProblemDetails
+	decodeJSON(t, w, &problem)
+	if problem.Code != code || problem.Status != status {
+		t.Errorf("problem = %+v, ожидали code=%s status=%d", problem, code, status)
+	}
+	return problem
+}
+
+func subscriptionJSON(userID uuid.UUID, service string, price int, start, end string) string {
+	body := map[string]interface{}{"service_name": service, "price": price, "user_id": userID, "start_date": start}
+	if end != "" {
+		body["end_date"] = end
+	}
+	raw, _ := json.Marshal(body)
+	return string(raw)
+}
+
+func createViaAPI(t *testing.T, r http.Handler, body string) subscriptionService.Subscription {
+	t.Helper()
+	w := doRequest(r, http.MethodPost, "/subscriptions", body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("создание: статус %d: %s", w.Code, w.Body.String())
+	}
+	var sub subscriptionService.Subscription
+	decodeJSON(t, w, &sub)
+	return sub
+}
+
+func subscriptionPath(id uint) string {
+	return "/subscriptions/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func TestPingAndMetrics(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+
+	w := doRequest(r, http.MethodGet, "/ping", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "pong") {
+		t.Errorf("/ping: %d %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodGet, "/metrics", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("/metrics: статус %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "http_request_duration_seconds") {
+		t.Errorf("в /metrics нет гистограммы запросов:\n%s", w.Body.String())
+	}
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+
+	created := createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 500, "01-2025", ""))
+	path := subscriptionPath(created.ID)
+
+	w := doRequest(r, http.MethodGet, path, "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET: статус %d, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// PUT с актуальной версией проходит, повтор с той же версией — 412
+	update := subscriptionJSON(userID, "Netflix", 600, "01-2025", "12-2025")
+	w = doRequest(r, http.MethodPut, path, update, map[string]string{"If-Match": `"1"`})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT: статус %d, ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+	w = doRequest(r, http.MethodPut, path, update, map[string]string{"If-Match": `"1"`})
+	requireProblem(t, w, http.StatusPreconditionFailed, subscriptionService.CodeVersionConflict)
+
+	// PATCH принимает только merge-patch (или обычный JSON)
+	w = doRequest(r, http.MethodPatch, path, `{"price":700}`, map[string]string{"Content-Type": "text/plain"})
+	requireProblem(t, w, http.StatusUnsupportedMediaType, codeUnsupportedMediaType)
+
+	// If-Match сравнивает теги строго: слабый ETag не совпадает даже с текущей версией
+	w = doRequest(r, http.MethodPatch, path, `{"price":700}`, map[string]string{
+		"Content-Type": "application/merge-patch+json",
+		"If-Match":     `W/"2"`,
+	})
+	requireProblem(t, w, http.StatusPreconditionFailed, subscriptionService.CodeVersionConflict)
+
+	w = doRequest(r, http.MethodPatch, path, `{"price":700,"end_date":null}`, map[string]string{
+		"Content-Type": "application/merge-patch+json",
+		"If-Match":     `"1", W/"2", "2"`,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: статус %d: %s", w.Code, w.Body.String())
+	}
+	var patched subscriptionService.Subscription
+	decodeJSON(t, w, &patched)
+	if patched.Price != 700 || patched.EndDate != nil || patched.Version != 3 {
+		t.Errorf("PATCH применился неверно: %+v", patched)
+	}
+
+	// Удаление, корзина, восстановление
+	w = doRequest(r, http.MethodDelete, path, "", nil)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: статус %d", w.Code)
+	}
+	requireProblem(t, doRequest(r, http.MethodGet, path, "", nil), http.StatusNotFound, subscriptionService.CodeSubscriptionNotFound)
+
+	w = doRequest(r, http.MethodGet, "/subscriptions/deleted", "", nil)
+	var deleted subscriptionService.DeletedPaginatedResponse
+	decodeJSON(t, w, &deleted)
+	if w.Code != http.StatusOK || len(deleted.Data) != 1 || deleted.Data[0].ID != created.ID {
+		t.Fatalf("корзина: статус %d, %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodPost, path+"/restore", "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"4"` {
+		t.Fatalf("restore: статус %d, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+	requireProblem(t, doRequest(r, http.MethodPost, path+"/restore", "", nil), http.StatusConflict, subscriptionService.CodeNotDeleted)
+
+	// Срок хранения по умолчанию 30 дней: только что удаленное не очищается
+	w = doRequest(r, http.MethodDelete, "/subscriptions/deleted", "", nil)
+	var purged subscriptionService.PurgeResult
+	decodeJSON(t, w, &purged)
+	if w.Code != http.StatusOK || purged.Purged != 0 {
+		t.Errorf("очистка: статус %d, %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSubscriptionErrors(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	created := createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 500, "01-2025", ""))
+	path := subscriptionPath(created.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		headers    map[string]string
+		wantStatus int
+		code       string
+	}{
+		{"create: не JSON", http.MethodPost, "/subscriptions", `{`, nil, http.StatusBadRequest, codeInvalidRequestBody},
+		{"create: нет обязательного поля", http.MethodPost, "/subscriptions", `{"service_name":"Netflix"}`, nil, http.StatusBadRequest, subscriptionService.CodeValidationFailed},
+		{"create: неверная дата", http.MethodPost, "/subscriptions", subscriptionJSON(userID, "Netflix", 500, "01.2025", ""), nil, http.StatusBadRequest, subscriptionService.CodeValidationFailed},
+		{"get: id не число", http.MethodGet, "/subscriptions/abc", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidID},
+		{"get: нет подписки", http.MethodGet, "/subscriptions/999", "", nil, http.StatusNotFound, subscriptionService.CodeSubscriptionNotFound},
+		{"put: плохой If-Match", http.MethodPut, path, subscriptionJSON(userID, "Netflix", 500, "01-2025", ""), map[string]string{"If-Match": "1"}, http.StatusBadRequest, codeInvalidIfMatch},
+		{"patch: не объект", http.MethodPatch, path, `[]`, nil, http.StatusBadRequest, subscriptionService.CodeInvalidPatch},
+		{"delete: нет подписки", http.MethodDelete, "/subscriptions/999", "", nil, http.StatusNotFound, subscriptionService.CodeSubscriptionNotFound},
+		{"list: page=0", http.MethodGet, "/subscriptions?page=0", "", nil, http.StatusBadRequest, codeInvalidPagination},
+		{"list: limit больше 100", http.MethodGet, "/subscriptions?limit=101", "", nil, http.StatusBadRequest, codeInvalidPagination},
+		{"list: неизвестная сортировка", http.MethodGet, "/subscriptions?sort=password", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidSort},
+		{"list: плохой курсор", http.MethodGet, "/subscriptions?cursor=abc", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidCursor},
+		{"deleted: page=x", http.MethodGet, "/subscriptions/deleted?page=x", "", nil, http.StatusBadRequest, codeInvalidPagination},
+		{"amount: нет дат", http.MethodGet, "/subscriptions/amountSubscriptions", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidStartDate},
+		{"amount: конец раньше начала", http.MethodGet, "/subscriptions/amountSubscriptions?start_date=05-2025&end_date=01-2025", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidDateRange},
+		{"amount: неизвестный режим", http.MethodGet, "/subscriptions/amountSubscriptions?start_date=01-2025&end_date=05-2025&mode=daily", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidMode},
+		{"amount: неизвестная группировка", http.MethodGet, "/subscriptions/amountSubscriptions?start_date=01-2025&end_date=05-2025&group_by=city", "", nil, http.StatusBadRequest, subscriptionService.CodeInvalidGroupBy},
+		{"export: неподдерживаемый Accept", http.MethodGet, "/subscriptions/export", "", map[string]string{"Accept": "application/pdf"}, http.StatusNotAcceptable, codeNotAcceptable},
+		{"export: неверный фильтр", http.MethodGet, "/subscriptions/export?min_price=x", "", map[string]string{"Accept": "text/csv"}, http.StatusBadRequest, subscriptionService.CodeInvalidFilter},
+		{"amount export: неподдерживаемый Accept", http.MethodGet, "/subscriptions/amountSubscriptions/export?start_date=01-2025&end_date=05-2025", "", map[string]string{"Accept": "application/pdf"}, http.StatusNotAcceptable, codeNotAcceptable},
+		{"batch: неизвестный метод", http.MethodPost, "/subscriptions:merge", `{"operations":[]}`, nil, http.StatusNotFound, codeRouteNotFound},
+		{"batch: внутренний путь", http.MethodPost, "/subscriptions/batch", `{"operations":[]}`, nil, http.StatusNotFound, codeRouteNotFound},
+		{"batch: пустой пакет", http.MethodPost, "/subscriptions:batch", `{"operations":[]}`, nil, http.StatusBadRequest, subscriptionService.CodeInvalidBatch},
+		{"import: плохой dry_run", http.MethodPost, "/subscriptions/import?dry_run=maybe", "", nil, http.StatusBadRequest, codeInvalidRequestBody},
+		{"restore: нет подписки", http.MethodPost, "/subscriptions/999/restore", "", nil, http.StatusNotFound, subscriptionService.CodeSubscriptionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, tt.method, tt.path, tt.body, tt.headers)
+			problem := requireProblem(t, w, tt.wantStatus, tt.code)
+
+			if problem.Instance != strings.SplitN(tt.path, "?", 2)[0] {
+				t.Errorf("instance = %q, ожидали путь запроса", problem.Instance)
+			}
+		})
+	}
+}
+
+func TestCustomMethods(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+
+	// Раньше ":batch" был параметром пути и сюда попадал любой POST /subscriptionsXYZ
+	for _, path := range []string{"/subscriptionsXYZ", "/subscriptions_batch"} {
+		if w := doRequest(r, http.MethodPost, path, `{"operations":[]}`, nil); w.Code != http.StatusNotFound {
+			t.Errorf("POST %s: статус %d, ожидали 404", path, w.Code)
+		}
+	}
+
+	w := doRequest(r, http.MethodPost, "/subscriptions:batch", `{"operations":[{"op":"delete","id":"1"}],"mode":"best_effort"}`, nil)
+	if w.Code != http.StatusMultiStatus {
+		t.Errorf("POST /subscriptions:batch: статус %d: %s", w.Code, w.Body.String())
+	}
+	// Прочие маршруты обертка не трогает
+	if w := doRequest(r, http.MethodGet, "/subscriptions", "", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /subscriptions: статус %d", w.Code)
+	}
+	// Двоеточие вне известных методов — обычная часть пути: ID проверяет обработчик
+	requireProblem(t, doRequest(r, http.MethodGet, "/subscriptions/1:2", "", nil), http.StatusBadRequest, subscriptionService.CodeInvalidID)
+}
+
+func TestFieldErrors(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	created := createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 500, "01-2025", ""))
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		fields []string // поле:код в порядке проверки
+	}{
+		{"нулевая цена", http.MethodPost, subscriptionJSON(userID, "Netflix", 0, "01-2025", ""), []string{"price:" + subscriptionService.CodeInvalidPrice}},
+		{"отрицательная цена при обновлении", http.MethodPut, subscriptionJSON(userID, "Netflix", -5, "01-2025", ""), []string{"price:" + subscriptionService.CodeInvalidPrice}},
+		{"окончание раньше начала", http.MethodPost, subscriptionJSON(userID, "Netflix", 500, "05-2025", "01-2025"), []string{"end_date:" + subscriptionService.CodeInvalidDateRange}},
+		{"неизвестная валюта", http.MethodPut, `{"service_name":"Netflix","price":500,"currency":"XXX","user_id":"` + userID.String() + `","start_date":"01-2025"}`, []string{"currency:" + subscriptionService.CodeInvalidCurrency}},
+		{"цена строкой", http.MethodPost, `{"service_name":"Netflix","price":"500","user_id":"` + userID.String() + `","start_date":"01-2025"}`, []string{"price:" + codeInvalidFieldType}},
+		{"пустое тело", http.MethodPost, `{}`, []string{
+			"service_name:" + subscriptionService.CodeRequiredField,
+			"price:" + subscriptionService.CodeInvalidPrice,
+			"user_id:" + subscriptionService.CodeRequiredField,
+			"start_date:" + subscriptionService.CodeRequiredField,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/subscriptions"
+			if tt.method == http.MethodPut {
+				path = subscriptionPath(created.ID)
+			}
+
+			problem := requireProblem(t, doRequest(r, tt.method, path, tt.body, nil), http.StatusBadRequest, subscriptionService.CodeValidationFailed)
+
+			got := make([]string, 0, len(problem.Errors))
+			for _, fe := range problem.Errors {
+				got = append(got, fe.Field+":"+fe.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("errors = %v, ожидали %v", got, tt.fields)
+			}
+		})
+	}
+
+	// Неудачное обновление не меняет подписку
+	w := doRequest(r, http.MethodGet, subscriptionPath(created.ID), "", nil)
+	if w.Header().Get("ETag") != `"1"` {
+		t.Errorf("после отклоненных обновлений ETag = %q", w.Header().Get("ETag"))
+	}
+}
+
+func TestListSubscriptions(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	for i, service := range []string{"Netflix", "Spotify", "Kinopoisk"} {
+		createViaAPI(t, r, subscriptionJSON(userID, service, 100*(i+1), "01-2025", ""))
+	}
+
+	w := doRequest(r, http.MethodGet, "/subscriptions?limit=2&sort=-price", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", w.Code, w.Body.String())
+	}
+	var page subscriptionService.PaginatedResponse
+	decodeJSON(t, w, &page)
+	if len(page.Data) != 2 || page.Data[0].ServiceName != "Kinopoisk" {
+		t.Errorf("первая страница: %+v", page.Data)
+	}
+	if *page.Meta.TotalItems != 3 || *page.Meta.TotalPages != 2 || page.Meta.Sort != "-price" {
+		t.Errorf("meta = %+v", page.Meta)
+	}
+
+	w = doRequest(r, http.MethodGet, "/subscriptions?service_name_prefix=Spot", "", nil)
+	decodeJSON(t, w, &page)
+	if len(page.Data) != 1 || page.Meta.Filters["service_name_prefix"] != "Spot" {
+		t.Errorf("фильтр по префиксу: %s", w.Body.String())
+	}
+
+	// Курсор: две страницы по две подписки, вторая — последняя
+	w = doRequest(r, http.MethodGet, "/subscriptions?pagination=cursor&limit=2", "", nil)
+	decodeJSON(t, w, &page)
+	if len(page.Data) != 2 || page.Meta.NextCursor == "" || page.Meta.PrevCursor != "" {
+		t.Fatalf("первая страница курсора: %s", w.Body.String())
+	}
+	w = doRequest(r, http.MethodGet, "/subscriptions?limit=2&cursor="+page.Meta.NextCursor, "", nil)
+	page = subscriptionService.PaginatedResponse{}
+	decodeJSON(t, w, &page)
+	if len(page.Data) != 1 || page.Meta.NextCursor != "" || page.Meta.PrevCursor == "" {
+		t.Errorf("вторая страница курсора: %s", w.Body.String())
+	}
+}
+
+func TestGetAmountOfsubscriptions(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 100, "01-2025", "03-2025"))
+	createViaAPI(t, r, subscriptionJSON(userID, "Spotify", 200, "06-2025", "06-2025"))
+	createViaAPI(t, r, subscriptionJSON(uuid.New(), "Spotify", 1000, "01-2025", "12-2025"))
+	createViaAPI(t, r, `{"service_name":"Netflix","price":9,"currency":"USD","user_id":"`+userID.String()+`","start_date":"01-2025","end_date":"01-2025"}`)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"весь год пользователя", "start_date=01-2025&end_date=12-2025&user_id=" + userID.String(), 500},
+		{"часть периода", "start_date=02-2025&end_date=05-2025&user_id=" + userID.String(), 200},
+		{"по сервису", "start_date=06-2025&end_date=06-2025&name_service=Spotify", 1200},
+		{"период без подписок", "start_date=01-2020&end_date=12-2020", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions?"+tt.query, "", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("статус %d: %s", w.Code, w.Body.String())
+			}
+			var breakdown subscriptionService.CostBreakdown
+			decodeJSON(t, w, &breakdown)
+			if breakdown.TotalPrice != tt.want {
+				t.Errorf("total_price = %d, ожидали %d", breakdown.TotalPrice, tt.want)
+			}
+		})
+	}
+
+	t.Run("валюты не складываются", func(t *testing.T) {
+		for currency, want := range map[string]int{"": 500, "RUB": 500, "USD": 9} {
+			w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions?start_date=01-2025&end_date=12-2025&currency="+currency+"&user_id="+userID.String(), "", nil)
+			var breakdown subscriptionService.CostBreakdown
+			decodeJSON(t, w, &breakdown)
+			wantCurrency := cmp.Or(currency, subscriptionService.DefaultCurrency)
+			if w.Code != http.StatusOK || breakdown.TotalPrice != want || breakdown.Currency != wantCurrency {
+				t.Errorf("currency=%q: статус %d, тело %s, ожидали %d %s", currency, w.Code, w.Body.String(), want, wantCurrency)
+			}
+		}
+
+		w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions?start_date=01-2025&end_date=12-2025&currency=XXX", "", nil)
+		requireProblem(t, w, http.StatusBadRequest, subscriptionService.CodeInvalidCurrency)
+	})
+
+	t.Run("группировка по сервису", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions?start_date=01-2025&end_date=12-2025&group_by=service_name&user_id="+userID.String(), "", nil)
+		var breakdown subscriptionService.CostBreakdown
+		decodeJSON(t, w, &breakdown)
+		if w.Code != http.StatusOK || breakdown.TotalPrice != 500 || len(breakdown.Groups) != 2 {
+			t.Fatalf("статус %d: %s", w.Code, w.Body.String())
+		}
+		for _, group := range breakdown.Groups {
+			if len(group.Series) != 12 {
+				t.Errorf("у группы %s %d месяцев, ожидали 12", group.Key, len(group.Series))
+			}
+		}
+	})
+}
+
+func TestDayPrecisionBilling(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	created := createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 100, "2025-01-31", "2025-04-15"))
+	if !created.StartDate.Equal(time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("start_date = %v", created.StartDate)
+	}
+
+	// Списания: 31.01, 28.02, 31.03 (30.04 уже после окончания)
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"весь период месяцами", "start_date=01-2025&end_date=12-2025", 300},
+		{"YYYY-MM", "start_date=2025-02&end_date=2025-04", 200},
+		{"до списания в марте", "start_date=2025-03-01&end_date=2025-03-30", 0},
+		{"день списания", "start_date=2025-02-28&end_date=2025-02-28", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions?"+tt.query, "", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("статус %d: %s", w.Code, w.Body.String())
+			}
+			var breakdown subscriptionService.CostBreakdown
+			decodeJSON(t, w, &breakdown)
+			if breakdown.TotalPrice != tt.want {
+				t.Errorf("total_price = %d, ожидали %d", breakdown.TotalPrice, tt.want)
+			}
+		})
+	}
+
+	w := doRequest(r, http.MethodGet, "/subscriptions?active_at=2025-04-16", "", nil)
+	var page subscriptionService.PaginatedResponse
+	decodeJSON(t, w, &page)
+	if w.Code != http.StatusOK || len(page.Data) != 0 {
+		t.Errorf("подписка действует после окончания: %s", w.Body.String())
+	}
+	w = doRequest(r, http.MethodGet, "/subscriptions?active_at=04-2025", "", nil)
+	page = subscriptionService.PaginatedResponse{}
+	decodeJSON(t, w, &page)
+	if w.Code != http.StatusOK || len(page.Data) != 1 {
+		t.Errorf("подписка не действует в апреле: %s", w.Body.String())
+	}
+}
+
+func TestProratedAmount(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	// Отменена 2 января: помесячно — полная цена, пропорционально — 2/31
+	createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 310, "2025-01-01", "2025-01-02"))
+	// Високосный февраль: 15 из 29 дней
+	createViaAPI(t, r, subscriptionJSON(userID, "Spotify", 290, "2024-02-15", "2024-02-29"))
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"по умолчанию помесячно", "start_date=01-2025&end_date=01-2025", 310},
+		{"monthly", "start_date=01-2025&end_date=01-2025&mode=monthly", 310},
+		{"prorated", "start_date=01-2025&end_date=01-2025&mode=prorated", 20},
+		{"prorated в високосном феврале", "start_date=02-2024&end_date=02-2024&mode=prorated", 150},
+		{"prorated по части периода", "start_date=2024-02-20&end_date=2024-02-29&mode=prorated", 100},
+		{"prorated с группировкой", "start_date=01-2024&end_date=12-2025&mode=prorated&group_by=service_name", 170},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions?"+tt.query, "", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("статус %d: %s", w.Code, w.Body.String())
+			}
+			var breakdown subscriptionService.CostBreakdown
+			decodeJSON(t, w, &breakdown)
+			if breakdown.TotalPrice != tt.want {
+				t.Errorf("total_price = %d, ожидали %d", breakdown.TotalPrice, tt.want)
+			}
+		})
+	}
+
+	w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions/export?start_date=01-2025&end_date=01-2025&mode=prorated", "", map[string]string{"Accept": "text/csv"})
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("статус %d: %v", w.Code, err)
+	}
+	if len(records) != 2 || records[1][len(records[1])-1] != "20" {
+		t.Errorf("выгрузка: %v", records)
+	}
+}
+
+func TestBatchSubscriptions(t *testing.T) {
+	userID := uuid.New()
+	valid := subscriptionJSON(userID, "Netflix", 500, "01-2025", "")
+	invalid := subscriptionJSON(userID, "Netflix", 500, "01.2025", "")
+
+	tests := []struct {
+		name          string
+		body          string
+		wantStatus    int
+		wantCommitted bool
+	}{
+		{"все операции успешны", `{"operations":[{"op":"create","data":` + valid + `},{"op":"create","data":` + valid + `}]}`, http.StatusOK, true},
+		{"atomic откатывается", `{"operations":[{"op":"create","data":` + valid + `},{"op":"create","data":` + invalid + `}]}`, http.StatusUnprocessableEntity, false},
+		{"best_effort с ошибкой", `{"mode":"best_effort","operations":[{"op":"create","data":` + valid + `},{"op":"delete","id":"999"}]}`, http.StatusMultiStatus, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, 0, 0)
+
+			w := doRequest(r, http.MethodPost, "/subscriptions:batch", tt.body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("статус %d, ожидали %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var response BatchResponse
+			decodeJSON(t, w, &response)
+			if response.Committed != tt.wantCommitted {
+				t.Errorf("committed = %v, ожидали %v", response.Committed, tt.wantCommitted)
+			}
+			for _, item := range response.Results {
+				if item.Status == subscriptionService.BatchItemFailed && (item.Error == nil || item.Error.Code == "") {
+					t.Errorf("у неудачной операции %d нет problem+json", item.Index)
+				}
+			}
+		})
+	}
+}
+
+func TestImportSubscriptions(t *testing.T) {
+	userID := uuid.New().String()
+	validCSV := "service_name,price,user_id,start_date,end_date\nNetflix,500," + userID + ",01-2025,\nSpotify,200," + userID + ",02-2025,06-2025\n"
+	invalidCSV := "service_name,price,user_id,start_date\nNetflix,много," + userID + ",01-2025\n"
+
+	multipartBody := func(content string) (string, string) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "subscriptions.csv")
+		part.Write([]byte(content))
+		writer.Close()
+		return buf.String(), writer.FormDataContentType()
+	}
+	formBody, formType := multipartBody(validCSV)
+
+	tests := []struct {
+		name         string
+		query        string
+		body         string
+		contentType  string
+		wantStatus   int
+		wantImported int
+		wantTotal    int64
+	}{
+		{"CSV в теле", "", validCSV, "text/csv", http.StatusOK, 2, 2},
+		{"multipart", "", formBody, formType, http.StatusOK, 2, 2},
+		{"dry run", "?dry_run=true", validCSV, "text/csv", http.StatusOK, 0, 0},
+		{"ошибка в строке", "", invalidCSV, "text/csv", http.StatusUnprocessableEntity, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, 0, 0)
+
+			w := doRequest(r, http.MethodPost, "/subscriptions/import"+tt.query, tt.body, map[string]string{"Content-Type": tt.contentType})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("статус %d, ожидали %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var result subscriptionService.ImportResult
+			decodeJSON(t, w, &result)
+			if result.Imported != tt.wantImported {
+				t.Errorf("imported = %d, ожидали %d", result.Imported, tt.wantImported)
+			}
+
+			var page subscriptionService.PaginatedResponse
+			decodeJSON(t, doRequest(r, http.MethodGet, "/subscriptions", "", nil), &page)
+			if *page.Meta.TotalItems != tt.wantTotal {
+				t.Errorf("в хранилище %d подписок, ожидали %d", *page.Meta.TotalItems, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestExportSubscriptions(t *testing.T) {
+	r := newTestRouter(t, 0, 0)
+	userID := uuid.New()
+	createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 500, "01-2025", "03-2025"))
+	createViaAPI(t, r, subscriptionJSON(userID, "Spotify", 200, "02-2025", ""))
+
+	t.Run("CSV", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/subscriptions/export?sort=price", "", map[string]string{"Accept": "text/csv"})
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), mimeCSV) {
+			t.Fatalf("статус %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Header().Get("Content-Disposition"), "subscriptions.csv") {
+			t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+		}
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(subscriptionCSVHeader, ",") {
+			t.Fatalf("CSV: %v", records)
+		}
+		if records[1][1] != "Spotify" || records[2][1] != "Netflix" {
+			t.Errorf("порядок строк не соответствует sort=price: %v", records[1:])
+		}
+		// Месяц в end_date сохраняется как его последний день
+		if records[2][5] != "2025-01-01" || records[2][6] != "2025-03-31" || records[1][6] != "" {
+			t.Errorf("даты в CSV: %v", records[1:])
+		}
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/subscriptions/export?service_name=Netflix", "", map[string]string{"Accept": mimeNDJSON})
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), mimeNDJSON) {
+			t.Fatalf("статус %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("ожидали одну строку, получили %d: %s", len(lines), w.Body.String())
+		}
+		var sub subscriptionService.Subscription
+		if err := json.Unmarshal([]byte(lines[0]), &sub); err != nil || sub.ServiceName != "Netflix" {
+			t.Errorf("строка NDJSON %q: %v", lines[0], err)
+		}
+	})
+
+	t.Run("пустая выгрузка", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/subscriptions/export?service_name=Okko", "", map[string]string{"Accept": "text/csv"})
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != strings.Join(subscriptionCSVHeader, ",") {
+			t.Errorf("статус %d, тело %q", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("суммы по месяцам", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/subscriptions/amountSubscriptions/export?start_date=01-2025&end_date=03-2025&group_by=service_name",
+			"", map[string]string{"Accept": "text/csv"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", w.Code, w.Body.String())
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Заголовок и по три месяца на каждый из двух сервисов
+		if len(records) != 7 || strings.Join(records[0], ",") != strings.Join(costCSVHeader, ",") {
+			t.Errorf("CSV: %v", records)
+		}
+	})
+}
+
+// failingStreamRepository отдает limit подписок, после чего чтение падает,
+// как при обрыве соединения с базой посреди выгрузки
+type failingStreamRepository struct {
+	subscriptionService.SubscriptionRepository
+	limit int
+}
+
+func (r failingStreamRepository) StreamSubscriptions(ctx context.Context, filter subscriptionService.ListFilter, fn func(subscriptionService.Subscription) error) error {
+	sent := 0
+	return r.SubscriptionRepository.StreamSubscriptions(ctx, filter, func(s subscriptionService.Subscription) error {
+		if sent == r.limit {
+			return errors.New("соединение с базой потеряно")
+		}
+		sent++
+		return fn(s)
+	})
+}
+
+func TestExportSubscriptionsFailsMidStream(t *testing.T) {
+	repo := failingStreamRepository{SubscriptionRepository: subscriptionService.NewMemoryRepository(), limit: exportFlushEvery + 1}
+	r := newTestRouterWithRepo(t, repo, 0, 0)
+	userID := uuid.New()
+	for i := 0; i < repo.limit+1; i++ {
+		createViaAPI(t, r, subscriptionJSON(userID, "Netflix", 100+i, "01-2025", ""))
+	}
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, accept := range []string{mimeCSV, mimeNDJSON} {
+		t.Run(accept, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/subscriptions/export", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", accept)
+
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("статус %d, ожидали 200: заголовки уходят с первой строкой", resp.StatusCode)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("ожидали обрыв соединения, получили ошибку %v и %d байт", err, len(body))
+			}
+			if len(body) == 0 {
+				t.Error("до обрыва клиент должен получить часть строк")
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	// Обычные запросы ограничены наносекундой и не успевают, долгие — без ограничения
+	r := newTestRouter(t, time.Nanosecond, 0)
+
+	requireProblem(t, doRequest(r, http.MethodGet, "/subscriptions", "", nil), http.StatusGatewayTimeout, subscriptionService.CodeRequestTimeout)
+	requireProblem(t, doRequest(r, http.MethodGet, "/subscriptions/1", "", nil), http.StatusGatewayTimeout, subscriptionService.CodeRequestTimeout)
+
+	w := doRequest(r, http.MethodGet, "/subscriptions/export", "", map[string]string{"Accept": "text/csv"})
+	if w.Code != http.StatusOK {
+		t.Errorf("выгрузка идет в группу долгих запросов, но получили %d: %s", w.Code, w.Body.String())
+	}
+}
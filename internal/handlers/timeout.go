@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout ограничивает время обработки запроса. По истечении d контекст
+// запроса отменяется: запросы к базе прерываются, и хендлер отвечает 504.
+// Если клиент закрыл соединение раньше, контекст отменяется сразу же.
+// d <= 0 — без ограничения.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SlowQueryThreshold — запросы дольше этого пишутся в лог с уровнем warn
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger пишет сообщения gorm через логгер запроса из контекста.
+// Ошибки SQL пишутся на уровне gorm error, медленные запросы — на warn,
+// текст всех запросов — на info и только в debug-лог.
+// Значения параметров в лог не попадают, только плейсхолдеры.
+func GormLogger(level logger.LogLevel) logger.Interface {
+	return &gormLogger{level: level}
+}
+
+type gormLogger struct {
+	level logger.LogLevel
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &gormLogger{level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	log := FromContext(ctx)
+	attrs := func() []slog.Attr {
+		sql, rows := fc()
+		return []slog.Attr{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		log.LogAttrs(ctx, slog.LevelError, "Ошибка SQL-запроса", append(attrs(), slog.String("error", err.Error()))...)
+	case elapsed > SlowQueryThreshold && l.level >= logger.Warn:
+		log.LogAttrs(ctx, slog.LevelWarn, "Медленный SQL-запрос", attrs()...)
+	case l.level >= logger.Info && log.Enabled(ctx, slog.LevelDebug):
+		log.LogAttrs(ctx, slog.LevelDebug, "SQL-запрос", attrs()...)
+	}
+}
+
+// ParamsFilter убирает значения параметров из текста запроса для лога
+func (l *gormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
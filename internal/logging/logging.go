@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// RedactedKeys — атрибуты, значения которых не пишутся в лог как есть.
+// Вместо значения пишется короткий хеш: записи одного пользователя
+// можно связать между собой, но сам идентификатор не раскрывается.
+var RedactedKeys = map[string]bool{
+	"user_id": true,
+}
+
+// ParseLevel переводит уровень из конфигурации (debug, info, warn, error) в slog.Level
+func ParseLevel(level string) slog.Level {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return parsed
+}
+
+// New создает JSON-логгер, пишущий в stdout
+func New(level string) *slog.Logger {
+	return NewWithWriter(os.Stdout, level)
+}
+
+// NewWithWriter создает JSON-логгер, пишущий в w
+func NewWithWriter(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       ParseLevel(level),
+		ReplaceAttr: redact,
+	}))
+}
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if !RedactedKeys[attr.Key] {
+		return attr
+	}
+	value := attr.Value.Resolve().String()
+	if value == "" {
+		return attr
+	}
+	sum := sha256.Sum256([]byte(value))
+	return slog.String(attr.Key, "sha256:"+hex.EncodeToString(sum[:6]))
+}
+
+type loggerKey struct{}
+
+// WithLogger кладет логгер в контекст
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext возвращает логгер запроса, а если его нет — логгер по умолчанию
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserIDIsRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter(&buf, "info")
+
+	userID := "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	logger.With("user_id", userID).Info("проверка")
+	logger.Info("проверка", "user_id", userID)
+
+	if strings.Contains(buf.String(), userID) {
+		t.Fatalf("user_id попал в лог: %s", buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var first, second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if first["user_id"] != second["user_id"] {
+		t.Errorf("хеш одного user_id отличается: %v и %v", first["user_id"], second["user_id"])
+	}
+}
+
+func TestGinMiddlewareRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(GinMiddleware(NewWithWriter(&buf, "info")))
+	r.GET("/ping", func(c *gin.Context) {
+		FromContext(c.Request.Context()).Info("внутри хендлера")
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "из заголовка", header: "req-123", keep: true},
+		{name: "без заголовка"},
+		{name: "некорректный заголовок", header: "bad id\n{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if requestID == "" {
+				t.Fatal("ответ без X-Request-ID")
+			}
+			if tt.keep && requestID != tt.header {
+				t.Errorf("X-Request-ID = %q, ожидали %q", requestID, tt.header)
+			}
+			if !tt.keep && requestID == tt.header {
+				t.Errorf("X-Request-ID %q должен был быть сгенерирован заново", requestID)
+			}
+
+			// И запись хендлера, и access-лог должны нести тот же request_id
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("ожидали 2 записи в логе, получили %d: %s", len(lines), buf.String())
+			}
+			for _, line := range lines {
+				var entry map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				if entry["request_id"] != requestID {
+					t.Errorf("request_id = %v, ожидали %q", entry["request_id"], requestID)
+				}
+			}
+		})
+	}
+}
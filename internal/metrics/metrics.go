@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	subscriptionService "rest_service/internal/subscriptionService"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "subscriptions"
+
+// Metrics — реестр метрик сервиса, который отдается на /metrics
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки HTTP-запросов по маршрутам gin.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Время выполнения запросов gorm.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"operation", "status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.queryDuration,
+	)
+	return m
+}
+
+// Handler отдает метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// GinMiddleware измеряет время запросов. Маршрут берется шаблоном (/subscriptions/:id),
+// чтобы число рядов не зависело от ID в URL.
+func (m *Metrics) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(started).Seconds())
+	}
+}
+
+// RegisterDBStats добавляет статистику пула соединений database/sql
+func (m *Metrics) RegisterDBStats(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterBusiness добавляет бизнес-метрики, которые считаются при каждом сборе
+func (m *Metrics) RegisterBusiness(service subscriptionService.SubscriptionService) {
+	m.registry.MustRegister(&businessCollector{service: service})
+}
+
+// GormPlugin возвращает плагин gorm, который измеряет время запросов
+func (m *Metrics) GormPlugin() gorm.Plugin {
+	return &gormPlugin{duration: m.queryDuration}
+}
+
+var (
+	activeSubscriptionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "active"),
+		"Число действующих в текущем месяце подписок по сервисам и валютам.",
+		[]string{"service_name", "currency"}, nil,
+	)
+	monthlyRevenueDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "monthly_recurring_revenue"),
+		"Сумма цен действующих в текущем месяце подписок (MRR) по валютам.",
+		[]string{"currency"}, nil,
+	)
+)
+
+// businessScrapeTimeout ограничивает запрос бизнес-метрик, чтобы медленная база
+// не задерживала весь сбор метрик
+const businessScrapeTimeout = 5 * time.Second
+
+// businessCollector запрашивает показатели из базы при каждом сборе метрик
+type businessCollector struct {
+	service subscriptionService.SubscriptionService
+}
+
+func (b *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSubscriptionsDesc
+	ch <- monthlyRevenueDesc
+}
+
+func (b *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessScrapeTimeout)
+	defer cancel()
+
+	stats, err := b.service.GetActiveSubscriptionStats(ctx)
+	if err != nil {
+		slog.Error("Не удалось посчитать бизнес-метрики", "error", err)
+		ch <- prometheus.NewInvalidMetric(activeSubscriptionsDesc, err)
+		return
+	}
+
+	// Выручку в разных валютах не складываем: MRR — отдельный ряд на каждую валюту
+	revenue := map[string]int64{}
+	for _, s := range stats {
+		ch <- prometheus.MustNewConstMetric(activeSubscriptionsDesc, prometheus.GaugeValue, float64(s.ActiveSubscriptions), s.ServiceName, s.Currency)
+		revenue[s.Currency] += s.MonthlyRevenue
+	}
+	for currency, total := range revenue {
+		ch <- prometheus.MustNewConstMetric(monthlyRevenueDesc, prometheus.GaugeValue, float64(total), currency)
+	}
+}
+
+const startedAtKey = "metrics:started_at"
+
+type gormPlugin struct {
+	duration *prometheus.HistogramVec
+}
+
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", p.before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", p.before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", p.before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", p.before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", p.after("raw")),
+	)
+}
+
+func (p *gormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+func (p *gormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startedAtKey)
+		if !ok {
+			return
+		}
+		started, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+		p.duration.WithLabelValues(operation, status).Observe(time.Since(started).Seconds())
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	subscriptionService "rest_service/internal/subscriptionService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsService отдает заранее заданную статистику; остальные методы не нужны
+type statsService struct {
+	subscriptionService.SubscriptionService
+	stats []subscriptionService.ServiceStats
+}
+
+func (s statsService) GetActiveSubscriptionStats(ctx context.Context) ([]subscriptionService.ServiceStats, error) {
+	return s.stats, nil
+}
+
+func TestMetricsExposeRoutesAndBusinessGauges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := New()
+	m.RegisterBusiness(statsService{stats: []subscriptionService.ServiceStats{
+		{ServiceName: "Netflix", Currency: "RUB", ActiveSubscriptions: 3, MonthlyRevenue: 1500},
+		{ServiceName: "Netflix", Currency: "USD", ActiveSubscriptions: 1, MonthlyRevenue: 15},
+		{ServiceName: "Yandex Plus", Currency: "RUB", ActiveSubscriptions: 2, MonthlyRevenue: 800},
+	}})
+
+	r := gin.New()
+	r.Use(m.GinMiddleware())
+	r.GET("/subscriptions/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/subscriptions/42", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`subscriptions_http_request_duration_seconds_count{method="GET",route="/subscriptions/:id",status="200"} 1`,
+		`subscriptions_active{currency="RUB",service_name="Netflix"} 3`,
+		`subscriptions_active{currency="USD",service_name="Netflix"} 1`,
+		`subscriptions_active{currency="RUB",service_name="Yandex Plus"} 2`,
+		`subscriptions_monthly_recurring_revenue{currency="RUB"} 2300`,
+		`subscriptions_monthly_recurring_revenue{currency="USD"} 15`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("в /metrics нет строки %q", want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey — ключ pg_advisory_lock, под которым выполняются миграции,
+// чтобы несколько реплик сервиса не применяли их одновременно
+const lockKey int64 = 7_350_210_001
+
+// Migration — одна версия схемы: пара файлов NNNN_name.up.sql / NNNN_name.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status — состояние схемы: текущая версия и еще не примененные миграции
+type Status struct {
+	Current int
+	Pending []Migration
+}
+
+// Load читает встроенные миграции, отсортированные по версии
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("неожиданный файл миграции %s", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, title, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("имя миграции %s должно иметь вид NNNN_name.%s.sql", name, direction)
+		}
+
+		body, err := files.ReadFile(path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if m.Name != title {
+			return nil, fmt.Errorf("у миграции %d разные имена: %s и %s", version, m.Name, title)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет up или down файла", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up применяет все еще не примененные миграции. Каждая миграция выполняется
+// в своей транзакции вместе с записью в schema_migrations.
+func Up(db *gorm.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		current, err := currentVersion(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if m.Version <= current {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name).Error
+			})
+			if err != nil {
+				return fmt.Errorf("миграция %04d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних примененных миграций
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("число откатываемых миграций должно быть положительным")
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]Migration{}
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var reverted []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		var versions []int
+		err := conn.Raw("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT ?", steps).
+			Scan(&versions).Error
+		if err != nil {
+			return err
+		}
+
+		for _, version := range versions {
+			m, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("в базе применена миграция %d, которой нет в сборке", version)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("откат миграции %04d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// GetStatus возвращает текущую версию схемы и список непримененных миграций
+func GetStatus(db *gorm.DB) (Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return Status{}, err
+	}
+
+	if err := ensureTable(db); err != nil {
+		return Status{}, err
+	}
+	current, err := currentVersion(db)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Current: current}
+	for _, m := range migrations {
+		if m.Version > current {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// Version возвращает текущую версию схемы, не изменяя базу
+func Version(db *gorm.DB) (int, error) {
+	return currentVersion(db)
+}
+
+// Latest — версия последней миграции, встроенной в сборку
+func Latest() (int, error) {
+	migrations, err := Load()
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// withLock выполняет fn на одном соединении, удерживая advisory lock.
+// Блокировка сессионная, поэтому все запросы должны идти через conn.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return fmt.Errorf("не удалось взять блокировку миграций: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`).Error
+}
+
+func currentVersion(db *gorm.DB) (int, error) {
+	var version int
+	err := db.Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version).Error
+	return version, err
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "testing"
+
+func TestLoadMigrationsAreSequential(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("нет ни одной миграции")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("миграция %s имеет версию %d, ожидалась %d", m.Name, m.Version, i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("у миграции %04d_%s пустой up или down", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package migrations_test
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"rest_service/internal/migrations"
+	"rest_service/internal/pgtest"
+
+	"gorm.io/gorm"
+)
+
+// TestMain останавливает временный Postgres, если его поднял pgtest
+func TestMain(m *testing.M) {
+	code := m.Run()
+	pgtest.Stop()
+	os.Exit(code)
+}
+
+// columnType — тип колонки subscriptions из information_schema; пустая
+// строка, если колонки (или таблицы) нет
+func columnType(t *testing.T, db *gorm.DB, column string) string {
+	t.Helper()
+	var dataType string
+	err := db.Raw(`SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'subscriptions' AND column_name = ?`, column).
+		Scan(&dataType).Error
+	if err != nil {
+		t.Fatalf("information_schema: %v", err)
+	}
+	return dataType
+}
+
+func requireVersion(t *testing.T, db *gorm.DB, want int) {
+	t.Helper()
+	got, err := migrations.Version(db)
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if got != want {
+		t.Fatalf("версия схемы %d, ожидали %d", got, want)
+	}
+}
+
+// TestUpDownRoundTrip применяет все миграции к пустой базе, откатывает их по
+// одной до нуля и применяет снова. Строка, сохраненная до отката 0005,
+// должна пережить смену типа дат в обе стороны.
+func TestUpDownRoundTrip(t *testing.T) {
+	db := pgtest.OpenEmpty(t)
+	latest, err := migrations.Latest()
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+
+	applied, err := migrations.Up(db)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != latest {
+		t.Fatalf("применено %d миграций, ожидали %d", len(applied), latest)
+	}
+	requireVersion(t, db, latest)
+	if got := columnType(t, db, "start_date"); got != "date" {
+		t.Errorf("start_date: %s, ожидали date", got)
+	}
+
+	err = db.Exec(`INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date)
+		VALUES ('Netflix', 500, '11111111-1111-4111-8111-111111111111', '2024-02-29', '2024-12-31')`).Error
+	if err != nil {
+		t.Fatalf("не удалось сохранить подписку: %v", err)
+	}
+
+	// 0005 вниз: даты снова timestamptz, день сохраняется по UTC
+	if _, err := migrations.Down(db, 1); err != nil {
+		t.Fatalf("Down 0005: %v", err)
+	}
+	requireVersion(t, db, latest-1)
+	if got := columnType(t, db, "start_date"); got != "timestamp with time zone" {
+		t.Errorf("start_date после отката 0005: %s, ожидали timestamp with time zone", got)
+	}
+	var start time.Time
+	if err := db.Raw("SELECT start_date FROM subscriptions").Scan(&start).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start_date после отката 0005: %s, ожидали %s", start, want)
+	}
+
+	// 0004 вниз: колонки currency и ограничений больше нет
+	if _, err := migrations.Down(db, 1); err != nil {
+		t.Fatalf("Down 0004: %v", err)
+	}
+	requireVersion(t, db, latest-2)
+	if got := columnType(t, db, "currency"); got != "" {
+		t.Errorf("currency после отката 0004: %s, ожидали, что колонки нет", got)
+	}
+
+	reverted, err := migrations.Down(db, latest)
+	if err != nil {
+		t.Fatalf("Down до нуля: %v", err)
+	}
+	if len(reverted) != latest-2 {
+		t.Errorf("откачено %d миграций, ожидали %d", len(reverted), latest-2)
+	}
+	requireVersion(t, db, 0)
+	if got := columnType(t, db, "id"); got != "" {
+		t.Errorf("таблица subscriptions осталась после отката всех миграций")
+	}
+
+	status, err := migrations.GetStatus(db)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if status.Current != 0 || len(status.Pending) != latest {
+		t.Errorf("состояние после отката: версия %d, ожидают %d миграций", status.Current, len(status.Pending))
+	}
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("повторный Up: %v", err)
+	}
+	requireVersion(t, db, latest)
+	if got := columnType(t, db, "end_date"); got != "date" {
+		t.Errorf("end_date после повторного Up: %s, ожидали date", got)
+	}
+	if status, err := migrations.GetStatus(db); err != nil || len(status.Pending) != 0 {
+		t.Errorf("после Up остались миграции: %+v, %v", status, err)
+	}
+}
+
+// TestConcurrentUpAppliesOnce запускает Up одновременно из нескольких
+// горутин: advisory lock пропускает их по одной, и каждая миграция
+// применяется ровно один раз
+func TestConcurrentUpAppliesOnce(t *testing.T) {
+	db := pgtest.OpenEmpty(t)
+	latest, err := migrations.Latest()
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+
+	const runners = 4
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied int
+	)
+	for i := 0; i < runners; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := migrations.Up(db)
+			if err != nil {
+				t.Errorf("Up: %v", err)
+			}
+			mu.Lock()
+			applied += len(done)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if applied != latest {
+		t.Errorf("всего применено %d миграций, ожидали %d", applied, latest)
+	}
+	requireVersion(t, db, latest)
+}
@@ -0,0 +1,14 @@
+-- Базовая схема. IF NOT EXISTS нужен для баз, созданных раньше через AutoMigrate.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id           bigserial PRIMARY KEY,
+    service_name text        NOT NULL,
+    price        bigint      NOT NULL,
+    user_id      uuid        NOT NULL,
+    start_date   timestamptz NOT NULL,
+    end_date     timestamptz,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_deleted_at ON subscriptions (deleted_at);
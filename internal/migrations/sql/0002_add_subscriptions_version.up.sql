@@ -0,0 +1,2 @@
+-- Версия строки для оптимистичной блокировки (ETag / If-Match)
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_currency_code,
+    DROP CONSTRAINT IF EXISTS subscriptions_service_name_length,
+    DROP CONSTRAINT IF EXISTS subscriptions_end_after_start,
+    DROP CONSTRAINT IF EXISTS subscriptions_price_positive;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,11 @@
+-- Валюта подписки; старые подписки оформлены в рублях
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RUB';
+
+-- Те же правила, что проверяет сервис (validation.go). NOT VALID: уже
+-- сохраненные строки не перепроверяются, иначе миграция упадет на старых данных,
+-- но новые и изменяемые строки обязаны соответствовать.
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_price_positive CHECK (price > 0) NOT VALID,
+    ADD CONSTRAINT subscriptions_end_after_start CHECK (end_date IS NULL OR end_date >= start_date) NOT VALID,
+    ADD CONSTRAINT subscriptions_service_name_length CHECK (char_length(service_name) BETWEEN 1 AND 100) NOT VALID,
+    ADD CONSTRAINT subscriptions_currency_code CHECK (currency ~ '^[A-Z]{3}$') NOT VALID;
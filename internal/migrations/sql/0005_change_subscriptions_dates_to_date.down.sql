@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE timestamptz USING start_date::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN end_date   TYPE timestamptz USING end_date::timestamp AT TIME ZONE 'UTC';
@@ -0,0 +1,6 @@
+-- Подписки считаются с точностью до дня: храним календарную дату, а не момент
+-- времени, чтобы часовой пояс сессии не сдвигал день. Старые значения
+-- переводятся по UTC — так их читал и считал сервис.
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE date USING (start_date AT TIME ZONE 'UTC')::date,
+    ALTER COLUMN end_date   TYPE date USING (end_date AT TIME ZONE 'UTC')::date;
@@ -0,0 +1,180 @@
+// Package pgtest дает интеграционным тестам настоящий Postgres без сети.
+//
+// Open подключается к TEST_DATABASE_DSN, а если он не задан — поднимает
+// временный кластер из локально установленного Postgres (initdb и pg_ctl из
+// PATH или /usr/lib/postgresql/*/bin). Кластер слушает только unix-сокет во
+// временном каталоге, поэтому не конфликтует с уже запущенной базой. Он один
+// на тестовый бинарник и останавливается в Stop, которую вызывает TestMain.
+//
+// Если нет ни DSN, ни установленного Postgres, тест пропускается. Так же
+// пропускается запуск от root без DSN: initdb и postgres от root не работают.
+// В этом случае базу дает make test-postgres (Postgres из docker-compose).
+package pgtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"rest_service/internal/migrations"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	errNoPostgres = errors.New("TEST_DATABASE_DSN не задан и Postgres (initdb, pg_ctl) не установлен")
+	errRunAsRoot  = errors.New("TEST_DATABASE_DSN не задан, а временный кластер нельзя поднять от root: initdb отказывается работать под root, используйте make test-postgres")
+)
+
+var (
+	once    sync.Once
+	db      *gorm.DB
+	dsn     string
+	openErr error
+
+	// Временный кластер, если он поднят
+	binDir string
+	dir    string
+)
+
+// Open возвращает подключение к базе с примененными миграциями.
+// Подключение общее для всех тестов бинарника: изменения нужно делать в
+// транзакции и откатывать.
+func Open(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	once.Do(func() { db, openErr = open() })
+	switch {
+	case errors.Is(openErr, errNoPostgres), errors.Is(openErr, errRunAsRoot):
+		tb.Skip(openErr)
+	case openErr != nil:
+		tb.Fatal(openErr)
+	}
+	return db
+}
+
+// OpenEmpty создает отдельную пустую базу без миграций и возвращает
+// подключение к ней. Нужна тестам, которые сами меняют схему (миграции):
+// общую базу из Open они не трогают. База удаляется в конце теста.
+func OpenEmpty(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	admin := Open(tb)
+	name := "pgtest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+		tb.Fatalf("не удалось создать базу %s: %v", name, err)
+	}
+
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	config.Database = name
+	sqlDB := stdlib.OpenDB(*config)
+	tb.Cleanup(func() {
+		sqlDB.Close()
+		if err := admin.Exec("DROP DATABASE IF EXISTS " + name).Error; err != nil {
+			tb.Errorf("не удалось удалить базу %s: %v", name, err)
+		}
+	})
+
+	conn, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatalf("не удалось подключиться к базе %s: %v", name, err)
+	}
+	return conn
+}
+
+// Stop останавливает временный кластер и удаляет его каталог
+func Stop() {
+	if db != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	if dir == "" {
+		return
+	}
+	exec.Command(filepath.Join(binDir, "pg_ctl"), "-D", filepath.Join(dir, "data"), "-m", "immediate", "stop").Run()
+	os.RemoveAll(dir)
+}
+
+func open() (*gorm.DB, error) {
+	dsn = os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		var err error
+		if dsn, err = startCluster(); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к базе: %w", err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		return nil, fmt.Errorf("не удалось выполнить миграции: %w", err)
+	}
+	return db, nil
+}
+
+// startCluster создает кластер во временном каталоге, запускает его и возвращает DSN
+func startCluster() (string, error) {
+	var err error
+	if binDir, err = findBinDir(); err != nil {
+		return "", err
+	}
+	if os.Geteuid() == 0 {
+		return "", errRunAsRoot
+	}
+	if dir, err = os.MkdirTemp("", "pgtest-"); err != nil {
+		return "", err
+	}
+	data := filepath.Join(dir, "data")
+
+	initdb := exec.Command(filepath.Join(binDir, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync")
+	if out, err := initdb.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("initdb: %w\n%s", err, out)
+	}
+
+	// Без TCP: только сокет в dir. fsync не нужен — кластер удаляется после тестов
+	options := fmt.Sprintf("-c listen_addresses='' -k %s -c fsync=off -c timezone=UTC", dir)
+	start := exec.Command(filepath.Join(binDir, "pg_ctl"), "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start")
+	if out, err := start.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pg_ctl start: %w\n%s", err, out)
+	}
+
+	return fmt.Sprintf("host=%s user=postgres dbname=postgres sslmode=disable", dir), nil
+}
+
+// findBinDir ищет каталог с initdb и pg_ctl: сначала в PATH, затем в
+// каталогах пакетов Debian/Ubuntu (берется самая новая версия)
+func findBinDir() (string, error) {
+	if path, err := exec.LookPath("pg_ctl"); err == nil {
+		return filepath.Dir(path), nil
+	}
+
+	candidates, _ := filepath.Glob("/usr/lib/postgresql/*/bin/pg_ctl")
+	if len(candidates) == 0 {
+		return "", errNoPostgres
+	}
+	// Версии — числа, сортируем по длине и строке, чтобы 9 шло раньше 15
+	slices.SortFunc(candidates, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	return filepath.Dir(candidates[len(candidates)-1]), nil
+}
@@ -0,0 +1,159 @@
+package subscriptionService
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// Режимы выполнения пакета операций
+const (
+	BatchModeAtomic     = "atomic"      // все операции в одной транзакции: либо все, либо ничего
+	BatchModeBestEffort = "best_effort" // каждая операция сама по себе
+)
+
+// Типы операций в пакете
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// Итог отдельной операции пакета
+const (
+	BatchItemSucceeded  = "succeeded"
+	BatchItemFailed     = "failed"
+	BatchItemRolledBack = "rolled_back" // выполнена, но отменена вместе с транзакцией
+	BatchItemSkipped    = "skipped"     // не выполнялась, потому что транзакция уже отменена
+)
+
+// MaxBatchOperations — максимальное число операций в одном пакете
+const MaxBatchOperations = 1000
+
+type BatchRequest struct {
+	Mode       string           `json:"mode" enums:"atomic,best_effort" default:"atomic"`
+	Operations []BatchOperation `json:"operations" binding:"required"`
+}
+
+type BatchOperation struct {
+	Op      string       `json:"op" enums:"create,update,delete"`
+	ID      string       `json:"id,omitempty"`      // для update и delete
+	Version *int         `json:"version,omitempty"` // ожидаемая версия для update, как If-Match
+	Data    *RequestBody `json:"data,omitempty"`    // для create и update
+}
+
+type BatchItemResult struct {
+	Index        int           `json:"index"`
+	Op           string        `json:"op"`
+	Status       string        `json:"status"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Err          error         `json:"-"`
+}
+
+type BatchResult struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// errBatchRolledBack прерывает транзакцию пакета после первой неудачной операции
+var errBatchRolledBack = errors.New("пакет операций отменен")
+
+// ExecuteBatch выполняет пакет операций создания, обновления и удаления.
+// Каждая операция проходит те же проверки, что и одиночный запрос.
+func (sub *subService) ExecuteBatch(ctx context.Context, req BatchRequest) (BatchResult, error) {
+
+	if req.Mode == "" {
+		req.Mode = BatchModeAtomic
+	}
+	if req.Mode != BatchModeAtomic && req.Mode != BatchModeBestEffort {
+		return BatchResult{}, newValidationError(CodeInvalidBatch, "mode должен быть atomic или best_effort")
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > MaxBatchOperations {
+		return BatchResult{}, newValidationError(CodeInvalidBatch, "operations должен содержать от 1 до "+strconv.Itoa(MaxBatchOperations)+" операций")
+	}
+
+	result := BatchResult{Mode: req.Mode, Results: make([]BatchItemResult, len(req.Operations))}
+	for i, op := range req.Operations {
+		result.Results[i] = BatchItemResult{Index: i, Op: op.Op, Status: BatchItemSkipped}
+	}
+
+	if req.Mode == BatchModeBestEffort {
+		for i, op := range req.Operations {
+			result.Results[i] = sub.executeBatchOperation(ctx, i, op)
+		}
+		result.Committed = true
+		return result, nil
+	}
+
+	err := sub.repo.WithinTransaction(ctx, func(txRepo SubscriptionRepository) error {
+		txService := &subService{repo: txRepo, purgeRetention: sub.purgeRetention}
+		for i, op := range req.Operations {
+			result.Results[i] = txService.executeBatchOperation(ctx, i, op)
+			if result.Results[i].Err != nil {
+				return errBatchRolledBack
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		result.Committed = true
+	case errors.Is(err, errBatchRolledBack):
+		for i := range result.Results {
+			if result.Results[i].Status == BatchItemSucceeded {
+				result.Results[i].Status = BatchItemRolledBack
+				result.Results[i].Subscription = nil
+			}
+		}
+	default:
+		return BatchResult{}, wrapRepoError(err, "не удалось выполнить пакет операций")
+	}
+
+	return result, nil
+}
+
+// executeBatchOperation выполняет одну операцию пакета через обычные методы сервиса
+func (sub *subService) executeBatchOperation(ctx context.Context, index int, op BatchOperation) BatchItemResult {
+	item := BatchItemResult{Index: index, Op: op.Op}
+
+	var (
+		subscription Subscription
+		err          error
+	)
+	switch op.Op {
+	case BatchOpCreate:
+		if op.Data == nil {
+			err = newValidationError(CodeInvalidBatch, "для create нужно поле data")
+			break
+		}
+		subscription, err = sub.CreateSubscriptions(ctx, *op.Data)
+	case BatchOpUpdate:
+		if op.Data == nil {
+			err = newValidationError(CodeInvalidBatch, "для update нужно поле data")
+			break
+		}
+		var expectedVersions []int
+		if op.Version != nil {
+			expectedVersions = []int{*op.Version}
+		}
+		subscription, err = sub.UpdateSubcriptionByID(ctx, *op.Data, op.ID, expectedVersions)
+	case BatchOpDelete:
+		err = sub.DeleteSubcriptionByID(ctx, op.ID)
+	default:
+		err = newValidationError(CodeInvalidBatch, "op должен быть create, update или delete")
+	}
+
+	if err != nil {
+		item.Status = BatchItemFailed
+		item.Err = err
+		return item
+	}
+
+	item.Status = BatchItemSucceeded
+	if op.Op != BatchOpDelete {
+		item.Subscription = &subscription
+	}
+	return item
+}
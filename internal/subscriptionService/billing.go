@@ -0,0 +1,69 @@
+package subscriptionService
+
+import "time"
+
+// Режимы расчета суммы подписок (параметр mode)
+const (
+	// CalculationMonthly — каждое ежемесячное списание внутри периода стоит полную цену
+	CalculationMonthly = "monthly"
+	// CalculationProrated — за каждый календарный месяц берется доля цены,
+	// пропорциональная числу дней, когда подписка действовала
+	CalculationProrated = "prorated"
+)
+
+// monthCost — часть стоимости подписки, отнесенная к месяцу
+type monthCost struct {
+	month  time.Time // первое число месяца
+	amount int
+}
+
+// subscriptionCosts раскладывает стоимость подписки за период по месяцам
+// в выбранном режиме. Из этих частей складываются и общая сумма, и разбивка по группам.
+func subscriptionCosts(s Subscription, params ParametersСalculatingSum, now time.Time) []monthCost {
+	if params.Mode == CalculationProrated {
+		return proratedCosts(s, params, now)
+	}
+
+	var costs []monthCost
+	first, last := chargeRange(s.StartDate, params.StartDate, chargeEnd(s, params, now))
+	for charge := first; charge <= last; charge++ {
+		costs = append(costs, monthCost{month: monthStart(addMonths(s.StartDate, charge)), amount: s.Price})
+	}
+	return costs
+}
+
+// proratedCosts считает стоимость подписки по дням: за календарный месяц
+// берется price × (дней действия в месяце) / (дней в месяце). Дни начала и
+// окончания входят в срок действия, бессрочная подписка действует по now
+// включительно. Доля каждого месяца округляется до целого (половина — вверх),
+// как в proratedCostSQL.
+func proratedCosts(s Subscription, params ParametersСalculatingSum, now time.Time) []monthCost {
+	from := s.StartDate.UTC()
+	if from.Before(params.StartDate) {
+		from = params.StartDate
+	}
+	end := chargeEnd(s, params, now)
+	to := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+
+	var costs []monthCost
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		first, last := month, monthEnd(month)
+		if from.After(first) {
+			first = from
+		}
+		if to.Before(last) {
+			last = to
+		}
+		if last.Before(first) {
+			continue // подписка начнется позже now
+		}
+		days := int(last.Sub(first).Hours()/24) + 1
+		costs = append(costs, monthCost{month: month, amount: prorate(s.Price, days, daysIn(month.Year(), month.Month()))})
+	}
+	return costs
+}
+
+// prorate — price × days / daysInMonth с округлением половины вверх
+func prorate(price, days, daysInMonth int) int {
+	return (2*price*days + daysInMonth) / (2 * daysInMonth)
+}
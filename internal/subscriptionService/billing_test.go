@@ -0,0 +1,137 @@
+package subscriptionService
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProrate(t *testing.T) {
+	tests := []struct {
+		price, days, daysInMonth, want int
+	}{
+		{310, 31, 31, 310},
+		{310, 2, 31, 20},
+		{290, 29, 29, 290},
+		{280, 14, 28, 140},
+		{15, 1, 30, 1}, // 0.5 округляется вверх
+		{14, 1, 30, 0}, // 0.47 — вниз
+		{100, 0, 31, 0},
+	}
+	for _, tt := range tests {
+		if got := prorate(tt.price, tt.days, tt.daysInMonth); got != tt.want {
+			t.Errorf("prorate(%d, %d, %d) = %d, ожидали %d", tt.price, tt.days, tt.daysInMonth, got, tt.want)
+		}
+	}
+}
+
+// proratedCase — подписка и её ожидаемая стоимость по месяцам в режиме prorated
+type proratedCase struct {
+	name string
+	sub  Subscription
+	want []monthCost
+}
+
+// proratedCases — високосные годы и границы месяцев для пропорционального
+// расчета. Общие для prorate в Go и proratedCostSQL (repository_test.go):
+// период — proratedPeriod, текущий момент — proratedNow.
+var (
+	proratedNow    = time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+	proratedPeriod = ParametersСalculatingSum{StartDate: month(2024, time.January), EndDate: day(2025, time.December, 31), Currency: DefaultCurrency, Mode: CalculationProrated}
+	proratedCases  = []proratedCase{
+		{
+			name: "отмена 2-го числа",
+			sub:  Subscription{Price: 310, StartDate: day(2025, time.January, 1), EndDate: dayPtr(2025, time.January, 2)},
+			want: []monthCost{{month(2025, time.January), 20}},
+		},
+		{
+			name: "целый месяц",
+			sub:  Subscription{Price: 310, StartDate: day(2025, time.March, 1), EndDate: dayPtr(2025, time.March, 31)},
+			want: []monthCost{{month(2025, time.March), 310}},
+		},
+		{
+			name: "весь високосный февраль",
+			sub:  Subscription{Price: 290, StartDate: day(2024, time.February, 1), EndDate: dayPtr(2024, time.February, 29)},
+			want: []monthCost{{month(2024, time.February), 290}},
+		},
+		{
+			name: "половина високосного февраля",
+			sub:  Subscription{Price: 290, StartDate: day(2024, time.February, 1), EndDate: dayPtr(2024, time.February, 15)},
+			want: []monthCost{{month(2024, time.February), 150}},
+		},
+		{
+			name: "половина обычного февраля",
+			sub:  Subscription{Price: 280, StartDate: day(2025, time.February, 15), EndDate: dayPtr(2025, time.February, 28)},
+			want: []monthCost{{month(2025, time.February), 140}},
+		},
+		{
+			name: "29 февраля",
+			sub:  Subscription{Price: 290, StartDate: day(2024, time.February, 29), EndDate: dayPtr(2024, time.February, 29)},
+			want: []monthCost{{month(2024, time.February), 10}},
+		},
+		{
+			name: "начало 31-го",
+			sub:  Subscription{Price: 310, StartDate: day(2025, time.January, 31), EndDate: dayPtr(2025, time.March, 2)},
+			want: []monthCost{{month(2025, time.January), 10}, {month(2025, time.February), 310}, {month(2025, time.March), 20}},
+		},
+		{
+			name: "через границу месяца",
+			sub:  Subscription{Price: 3100, StartDate: day(2025, time.January, 31), EndDate: dayPtr(2025, time.February, 1)},
+			want: []monthCost{{month(2025, time.January), 100}, {month(2025, time.February), 111}},
+		},
+		{
+			name: "через границу года",
+			sub:  Subscription{Price: 310, StartDate: day(2024, time.December, 31), EndDate: dayPtr(2025, time.January, 1)},
+			want: []monthCost{{month(2024, time.December), 10}, {month(2025, time.January), 10}},
+		},
+		{
+			name: "бессрочная считается по now",
+			sub:  Subscription{Price: 300, StartDate: day(2025, time.June, 1)},
+			want: []monthCost{{month(2025, time.June), 150}},
+		},
+		{
+			name: "начнется после now",
+			sub:  Subscription{Price: 300, StartDate: day(2025, time.June, 20)},
+		},
+		{
+			name: "закончилась до периода",
+			sub:  Subscription{Price: 300, StartDate: day(2023, time.January, 10), EndDate: dayPtr(2023, time.December, 31)},
+		},
+	}
+)
+
+func TestProratedCosts(t *testing.T) {
+	now, params := proratedNow, proratedPeriod
+	for _, tt := range proratedCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proratedCosts(tt.sub, params, now); !slices.Equal(got, tt.want) {
+				t.Errorf("получили %v, ожидали %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("обрезается периодом", func(t *testing.T) {
+		sub := Subscription{Price: 100, StartDate: day(2023, time.June, 15), EndDate: dayPtr(2026, time.March, 10)}
+		costs := proratedCosts(sub, params, now)
+		if len(costs) != 24 || !costs[0].month.Equal(month(2024, time.January)) || !costs[23].month.Equal(month(2025, time.December)) {
+			t.Fatalf("месяцы: %v", costs)
+		}
+		if total := calculateTotal([]Subscription{sub}, params, now); total != 2400 {
+			t.Errorf("сумма = %d, ожидали 2400", total)
+		}
+	})
+
+	// За целые месяцы пропорциональный расчет совпадает с помесячным —
+	// и в високосном, и в обычном году
+	t.Run("целые месяцы как в помесячном режиме", func(t *testing.T) {
+		for _, year := range []int{2024, 2025} {
+			sub := Subscription{Price: 100, StartDate: month(year, time.January), EndDate: dayPtr(year, time.December, 31)}
+			period := ParametersСalculatingSum{StartDate: month(year, time.January), EndDate: day(year, time.December, 31)}
+			monthly := calculateTotal([]Subscription{sub}, period, now)
+			period.Mode = CalculationProrated
+			if prorated := calculateTotal([]Subscription{sub}, period, now); prorated != monthly || prorated != 1200 {
+				t.Errorf("%d: пропорционально %d, помесячно %d, ожидали 1200", year, prorated, monthly)
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package subscriptionService
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Допустимые значения параметра group_by
+const (
+	GroupByServiceName = "service_name"
+	GroupByUserID      = "user_id"
+	GroupByMonth       = "month"
+)
+
+// CostBreakdown — сумма подписок за период в одной валюте, при необходимости
+// разбитая по группам
+type CostBreakdown struct {
+	TotalPrice int         `json:"total_price"`
+	Currency   string      `json:"currency"`
+	GroupBy    string      `json:"group_by,omitempty"`
+	Groups     []CostGroup `json:"groups,omitempty"`
+}
+
+// CostGroup — сумма по одной группе и её помесячная динамика
+type CostGroup struct {
+	Key        string        `json:"key"`
+	TotalPrice int           `json:"total_price"`
+	Series     []MonthlyCost `json:"series"`
+}
+
+// MonthlyCost — сумма за один месяц
+type MonthlyCost struct {
+	Month      string `json:"month"` // формат "MM-YYYY"
+	TotalPrice int    `json:"total_price"`
+}
+
+func (subService *subService) GetCostBreakdown(ctx context.Context, params RequestParametersСalculatingSum, groupBy string) (CostBreakdown, error) {
+
+	if _, ok := groupKeyColumns[groupBy]; !ok {
+		return CostBreakdown{}, newValidationError(CodeInvalidGroupBy, "group_by должен быть одним из: service_name, user_id, month")
+	}
+
+	validParams, err := parseSumParameters(params)
+	if err != nil {
+		return CostBreakdown{}, err
+	}
+
+	rows, err := subService.repo.MonthlyCostByGroup(ctx, validParams, groupBy, time.Now().UTC())
+	if err != nil {
+		return CostBreakdown{}, wrapRepoError(err, "не удалось посчитать сумму подписок")
+	}
+
+	return buildBreakdown(rows, groupBy, validParams), nil
+}
+
+// buildBreakdown собирает строки «группа × месяц» в группы с помесячными рядами.
+// Для группировки по сервису и пользователю ряд заполняется нулями за месяцы
+// без расходов, чтобы его можно было сразу выводить на график.
+func buildBreakdown(rows []MonthlyCostRow, groupBy string, params ParametersСalculatingSum) CostBreakdown {
+	breakdown := CostBreakdown{Currency: params.Currency, GroupBy: groupBy, Groups: []CostGroup{}}
+
+	byKey := map[string]map[string]int{}
+	for _, row := range rows {
+		if _, ok := byKey[row.GroupKey]; !ok {
+			byKey[row.GroupKey] = map[string]int{}
+			breakdown.Groups = append(breakdown.Groups, CostGroup{Key: row.GroupKey})
+		}
+		byKey[row.GroupKey][row.Month.Format("01-2006")] += int(row.Total)
+		breakdown.TotalPrice += int(row.Total)
+	}
+
+	for i := range breakdown.Groups {
+		group := &breakdown.Groups[i]
+		totals := byKey[group.Key]
+
+		if groupBy == GroupByMonth {
+			group.TotalPrice = totals[group.Key]
+			group.Series = []MonthlyCost{{Month: group.Key, TotalPrice: group.TotalPrice}}
+			continue
+		}
+
+		for month := monthStart(params.StartDate); !month.After(params.EndDate); month = month.AddDate(0, 1, 0) {
+			key := month.Format("01-2006")
+			group.Series = append(group.Series, MonthlyCost{Month: key, TotalPrice: totals[key]})
+			group.TotalPrice += totals[key]
+		}
+	}
+
+	return breakdown
+}
+
+// monthlyCostRows раскладывает стоимость подписок за период по месяцам
+// (subscriptionCosts) и суммирует по группе и месяцу — так же, как MonthlyCostByGroup в SQL.
+// Порядок строк тоже совпадает: по группе и месяцу, для group_by=month — по месяцу.
+func monthlyCostRows(subs []Subscription, params ParametersСalculatingSum, groupBy string, now time.Time) []MonthlyCostRow {
+	type rowKey struct {
+		group string
+		month time.Time
+	}
+	totals := map[rowKey]int64{}
+
+	for _, s := range subs {
+		for _, cost := range subscriptionCosts(s, params, now) {
+			key := rowKey{month: cost.month}
+			switch groupBy {
+			case GroupByServiceName:
+				key.group = s.ServiceName
+			case GroupByUserID:
+				key.group = s.UserID.String()
+			default:
+				key.group = cost.month.Format("01-2006")
+			}
+			totals[key] += int64(cost.amount)
+		}
+	}
+
+	rows := make([]MonthlyCostRow, 0, len(totals))
+	for key, total := range totals {
+		rows = append(rows, MonthlyCostRow{GroupKey: key.group, Month: key.month, Total: total})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if groupBy != GroupByMonth && rows[i].GroupKey != rows[j].GroupKey {
+			return rows[i].GroupKey < rows[j].GroupKey
+		}
+		return rows[i].Month.Before(rows[j].Month)
+	})
+	return rows
+}
+
+// monthStart — первое число месяца в UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
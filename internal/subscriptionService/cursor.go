@@ -0,0 +1,93 @@
+package subscriptionService
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// Направления перехода по курсору
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+// PageCursor — позиция в списке подписок, упорядоченном по (created_at, id).
+// Клиенту передается в виде непрозрачной строки.
+type PageCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uint      `json:"i"`
+	Direction string    `json:"d"`
+}
+
+func encodeCursor(sub Subscription, direction string) string {
+	raw, _ := json.Marshal(PageCursor{CreatedAt: sub.CreatedAt, ID: sub.ID, Direction: direction})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (PageCursor, error) {
+	invalid := newValidationError(CodeInvalidCursor, "некорректный cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return PageCursor{}, invalid
+	}
+
+	var cursor PageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return PageCursor{}, invalid
+	}
+	if cursor.ID == 0 || (cursor.Direction != CursorNext && cursor.Direction != CursorPrev) {
+		return PageCursor{}, invalid
+	}
+	return cursor, nil
+}
+
+// listByCursor возвращает страницу подписок, начиная с позиции курсора.
+// Без курсора возвращается первая страница.
+func (sub *subService) listByCursor(ctx context.Context, filter ListFilter, rawCursor string) (PaginatedResponse, error) {
+
+	var cursor *PageCursor
+	if rawCursor != "" {
+		decoded, err := decodeCursor(rawCursor)
+		if err != nil {
+			return PaginatedResponse{}, err
+		}
+		cursor = &decoded
+	}
+
+	backward := cursor != nil && cursor.Direction == CursorPrev
+
+	// Берем на одну запись больше, чтобы понять, есть ли что-то дальше
+	subscriptions, err := sub.repo.ListSubscriptionsByCursor(ctx, filter, cursor, filter.Limit+1)
+	if err != nil {
+		return PaginatedResponse{}, wrapRepoError(err, "не удалось получить список подписок")
+	}
+
+	hasMore := len(subscriptions) > filter.Limit
+	if hasMore {
+		subscriptions = subscriptions[:filter.Limit]
+	}
+	if backward {
+		for i, j := 0, len(subscriptions)-1; i < j; i, j = i+1, j-1 {
+			subscriptions[i], subscriptions[j] = subscriptions[j], subscriptions[i]
+		}
+	}
+
+	meta := PaginationMeta{Limit: filter.Limit}
+	if len(subscriptions) > 0 {
+		first, last := subscriptions[0], subscriptions[len(subscriptions)-1]
+
+		// Вперед можно идти, если за страницей есть записи или мы пришли назад;
+		// назад — если перед страницей есть записи или мы пришли вперед по курсору.
+		if hasMore || backward {
+			meta.NextCursor = encodeCursor(last, CursorNext)
+		}
+		if (backward && hasMore) || (!backward && cursor != nil) {
+			meta.PrevCursor = encodeCursor(first, CursorPrev)
+		}
+	}
+
+	return PaginatedResponse{Data: subscriptions, Meta: meta}, nil
+}
@@ -0,0 +1,99 @@
+package subscriptionService
+
+import (
+	"errors"
+	"time"
+)
+
+// DateLayout — формат дат в ответах API и выгрузках
+const DateLayout = "2006-01-02"
+
+// DateFormatsHint — подсказка о допустимых форматах дат для сообщений об ошибках
+const DateFormatsHint = "YYYY-MM-DD, YYYY-MM или MM-YYYY"
+
+var errInvalidDate = errors.New("неизвестный формат даты")
+
+// Форматы, в которых указан только месяц: день выбирает parseDate
+var monthLayouts = []string{"2006-01", "01-2006"}
+
+// parseDate разбирает дату в одном из форматов: YYYY-MM-DD, дата и время
+// по RFC 3339, YYYY-MM или MM-YYYY. Результат — полночь в UTC того дня,
+// который написан в строке. Если указан только месяц, возвращается его
+// первый день, а при endOfMonth — последний: так "12-2025" в end_date
+// означает «до конца декабря».
+func parseDate(value string, endOfMonth bool) (time.Time, error) {
+	for _, layout := range []string{DateLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+
+	for _, layout := range monthLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if endOfMonth {
+				return monthEnd(t), nil
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errInvalidDate
+}
+
+// formatDate представляет дату подписки в формате API
+func formatDate(t time.Time) string {
+	return t.UTC().Format(DateLayout)
+}
+
+// monthEnd — последний день месяца в UTC
+func monthEnd(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), daysIn(t.Year(), t.Month()), 0, 0, 0, 0, time.UTC)
+}
+
+// daysIn — число дней в месяце
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// addMonths сдвигает дату на n месяцев. Если такого дня в месяце нет,
+// берется последний день месяца: 31 января + 1 месяц = 28 (29) февраля.
+// Сдвиг всегда считается от исходной даты, поэтому 31 января + 2 месяца = 31 марта.
+// Так же прибавляет interval 'n month' Postgres.
+func addMonths(t time.Time, n int) time.Time {
+	t = t.UTC()
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	day := min(t.Day(), daysIn(first.Year(), first.Month()))
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// chargeRange возвращает номера списаний подписки, попадающих в [from, to]
+// (по дням, включительно). Подписка списывается в день начала и далее каждый
+// месяц в тот же день (addMonths(start, k), k = 0, 1, ...). Если списаний
+// в интервале нет, last < first.
+//
+// SumSubscriptionsPrice и MonthlyCostByGroup считают то же самое в SQL
+// (periodsForSum, monthDiffSQL и chargeDaySQL), формулы должны совпадать.
+func chargeRange(start, from, to time.Time) (first, last int) {
+	start, from, to = start.UTC(), from.UTC(), to.UTC()
+
+	// k-е списание приходится на месяц start + k; в месяце даты x это
+	// день min(день start, дней в месяце x)
+	monthDiff := func(x time.Time) int {
+		return (x.Year()-start.Year())*12 + int(x.Month()) - int(start.Month())
+	}
+	chargeDay := func(x time.Time) int {
+		return min(start.Day(), daysIn(x.Year(), x.Month()))
+	}
+
+	first = monthDiff(from)
+	if chargeDay(from) < from.Day() {
+		first++ // списание этого месяца уже прошло
+	}
+	first = max(first, 0)
+
+	last = monthDiff(to)
+	if chargeDay(to) > to.Day() {
+		last-- // списание этого месяца еще не наступило
+	}
+	return first, last
+}
@@ -0,0 +1,131 @@
+package subscriptionService
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value      string
+		endOfMonth bool
+		want       time.Time
+		wantErr    bool
+	}{
+		{value: "2025-07-15", want: day(2025, time.July, 15)},
+		{value: "2025-07-15", endOfMonth: true, want: day(2025, time.July, 15)},
+		{value: "2025-07-15T00:30:00Z", want: day(2025, time.July, 15)},
+		// День берется таким, как он записан, без перевода в UTC
+		{value: "2025-07-15T23:30:00-05:00", want: day(2025, time.July, 15)},
+		{value: "2025-07", want: month(2025, time.July)},
+		{value: "2025-07", endOfMonth: true, want: day(2025, time.July, 31)},
+		{value: "07-2025", want: month(2025, time.July)},
+		{value: "02-2024", endOfMonth: true, want: day(2024, time.February, 29)},
+		{value: "02-2025", endOfMonth: true, want: day(2025, time.February, 28)},
+		{value: "2024-02-29", want: day(2024, time.February, 29)},
+		{value: "2025-02-29", wantErr: true},
+		{value: "7-2025", wantErr: true},
+		{value: "2025-7", wantErr: true},
+		{value: "13-2025", wantErr: true},
+		{value: "15.07.2025", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDate(tt.value, tt.endOfMonth)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ожидали ошибку, получили %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("parseDate(%q, %v) = %v, ожидали %v", tt.value, tt.endOfMonth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		n     int
+		want  time.Time
+	}{
+		{"середина месяца", day(2025, time.January, 15), 1, day(2025, time.February, 15)},
+		{"31 января в феврале", day(2025, time.January, 31), 1, day(2025, time.February, 28)},
+		{"31 января в високосном феврале", day(2024, time.January, 31), 1, day(2024, time.February, 29)},
+		{"после короткого месяца день восстанавливается", day(2025, time.January, 31), 2, day(2025, time.March, 31)},
+		{"31-е в тридцатидневном месяце", day(2025, time.March, 31), 1, day(2025, time.April, 30)},
+		{"29 февраля через год", day(2024, time.February, 29), 12, day(2025, time.February, 28)},
+		{"29 февраля через четыре года", day(2024, time.February, 29), 48, day(2028, time.February, 29)},
+		{"через границу года", day(2024, time.December, 31), 2, day(2025, time.February, 28)},
+		{"ноль месяцев", day(2025, time.May, 5), 0, day(2025, time.May, 5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addMonths(tt.start, tt.n); !got.Equal(tt.want) {
+				t.Errorf("addMonths(%s, %d) = %s, ожидали %s", formatDate(tt.start), tt.n, formatDate(got), formatDate(tt.want))
+			}
+		})
+	}
+}
+
+func TestChargeRange(t *testing.T) {
+	tests := []struct {
+		name                string
+		start, from, to     time.Time
+		wantFirst, wantLast int
+	}{
+		{"целые месяцы", month(2025, time.January), month(2025, time.January), month(2025, time.December), 0, 11},
+		{"период с середины месяца", day(2025, time.January, 10), day(2025, time.March, 11), day(2025, time.May, 9), 3, 3},
+		{"списание в первый день периода", day(2025, time.January, 10), day(2025, time.March, 10), day(2025, time.March, 10), 2, 2},
+		{"списание в последний день периода", day(2025, time.January, 10), day(2025, time.February, 11), day(2025, time.March, 10), 2, 2},
+		{"между списаниями", day(2025, time.January, 10), day(2025, time.January, 11), day(2025, time.February, 9), 1, 0},
+		{"период до начала", day(2025, time.June, 1), month(2025, time.January), day(2025, time.March, 31), 0, -3},
+		{"31-е в феврале", day(2025, time.January, 31), day(2025, time.February, 28), day(2025, time.February, 28), 1, 1},
+		{"31-е в високосном феврале", day(2024, time.January, 31), day(2024, time.February, 28), day(2024, time.February, 28), 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last := chargeRange(tt.start, tt.from, tt.to)
+			if first != tt.wantFirst || last != tt.wantLast {
+				t.Errorf("chargeRange = [%d, %d], ожидали [%d, %d]", first, last, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+
+	// Сверяем формулу с перебором списаний по дням начала 1–31
+	// и всем периодам внутри двух лет, включая високосный февраль
+	t.Run("совпадает с перебором", func(t *testing.T) {
+		countCharges := func(start, from, to time.Time) int {
+			count := 0
+			for k := 0; ; k++ {
+				charge := addMonths(start, k)
+				if charge.After(to) {
+					return count
+				}
+				if !charge.Before(from) {
+					count++
+				}
+			}
+		}
+
+		for startDay := 1; startDay <= 31; startDay++ {
+			start := day(2024, time.January, startDay)
+			for from := start.AddDate(0, 0, -40); from.Before(day(2025, time.December, 31)); from = from.AddDate(0, 0, 17) {
+				for to := from; to.Before(day(2026, time.January, 31)); to = to.AddDate(0, 0, 23) {
+					first, last := chargeRange(start, from, to)
+					if got, want := max(0, last-first+1), countCharges(start, from, to); got != want {
+						t.Fatalf("start %s, [%s, %s]: %d списаний, перебор дает %d",
+							formatDate(start), formatDate(from), formatDate(to), got, want)
+					}
+				}
+			}
+		}
+	})
+}
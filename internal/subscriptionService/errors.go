@@ -0,0 +1,135 @@
+package subscriptionService
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Категории ошибок сервиса. Проверяются через errors.Is,
+// по ним слой хендлеров выбирает HTTP-статус.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict")
+	ErrInternal   = errors.New("internal error")
+	ErrTimeout    = errors.New("timeout")  // запрос не уложился в отведенное время
+	ErrCanceled   = errors.New("canceled") // клиент закрыл соединение, не дождавшись ответа
+)
+
+// Стабильные коды ошибок, на которые могут опираться клиенты API
+const (
+	CodeSubscriptionNotFound = "subscription_not_found"
+	CodeInvalidID            = "invalid_subscription_id"
+	CodeInvalidStartDate     = "invalid_start_date"
+	CodeInvalidEndDate       = "invalid_end_date"
+	CodeInvalidDateRange     = "invalid_date_range"
+	CodeInvalidUserID        = "invalid_user_id"
+	CodeInvalidGroupBy       = "invalid_group_by"
+	CodeInvalidMode          = "invalid_calculation_mode"
+	CodeInvalidFilter        = "invalid_filter"
+	CodeInvalidSort          = "invalid_sort"
+	CodeInvalidCursor        = "invalid_cursor"
+	CodeInvalidPatch         = "invalid_patch"
+	CodeRequiredField        = "required_field_missing"
+	CodeInvalidBatch         = "invalid_batch"
+	CodeInvalidCSV           = "invalid_csv"
+	CodeInvalidPrice         = "invalid_price"
+	CodeInvalidServiceName   = "invalid_service_name"
+	CodeInvalidCurrency      = "invalid_currency"
+	CodeValidationFailed     = "validation_failed" // подробности — в Error.Fields
+	CodeVersionConflict      = "version_conflict"
+	CodeNotDeleted           = "subscription_not_deleted"
+	CodeRequestTimeout       = "request_timeout"
+	CodeRequestCanceled      = "request_canceled"
+	CodeInternal             = "internal_error"
+)
+
+// Error — доменная ошибка сервиса подписок
+type Error struct {
+	Kind    error        // одна из ErrNotFound, ErrValidation, ErrConflict, ErrTimeout, ErrCanceled, ErrInternal
+	Code    string       // стабильный машиночитаемый код
+	Message string       // описание, которое можно показать клиенту
+	Err     error        // исходная причина, клиенту не показывается
+	Fields  []FieldError // ошибки отдельных полей, если запрос не прошел валидацию
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap позволяет проверять и категорию, и исходную причину через errors.Is
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func newNotFoundError(code, message string) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+func newValidationError(code, message string) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message}
+}
+
+func newInternalError(message string, err error) *Error {
+	return &Error{Kind: ErrInternal, Code: CodeInternal, Message: message, Err: err}
+}
+
+// ErrVersionConflict возвращается, если подписка была изменена другим клиентом
+// после того, как её прочитали (версия не совпадает с ожидаемой).
+var ErrVersionConflict = &Error{
+	Kind:    ErrConflict,
+	Code:    CodeVersionConflict,
+	Message: "подписка была изменена другим запросом",
+}
+
+// ErrSubscriptionNotDeleted возвращается при попытке восстановить подписку,
+// которая не была удалена.
+var ErrSubscriptionNotDeleted = &Error{
+	Kind:    ErrConflict,
+	Code:    CodeNotDeleted,
+	Message: "подписка не удалена",
+}
+
+// pgQueryCanceled — SQLSTATE, с которым Postgres прерывает запрос по statement_timeout
+const pgQueryCanceled = "57014"
+
+// wrapRepoError приводит ошибку репозитория к доменной
+func wrapRepoError(err error, message string) error {
+	var domainErr *Error
+	switch {
+	case errors.As(err, &domainErr):
+		return domainErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return newNotFoundError(CodeSubscriptionNotFound, "подписка не найдена")
+	default:
+		if ctxErr := contextError(err); ctxErr != nil {
+			return ctxErr
+		}
+		return newInternalError(message, err)
+	}
+}
+
+// contextError распознает прерывание запроса: истекший таймаут
+// (контекста или statement_timeout в Postgres) и отмену клиентом.
+// Для остальных ошибок возвращает nil.
+func contextError(err error) *Error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &pgErr) && pgErr.Code == pgQueryCanceled:
+		return &Error{Kind: ErrTimeout, Code: CodeRequestTimeout, Message: "запрос выполнялся слишком долго", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Kind: ErrCanceled, Code: CodeRequestCanceled, Message: "запрос отменен клиентом", Err: err}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,43 @@
+package subscriptionService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+func TestWrapRepoError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+		wantCode string
+	}{
+		{"не найдено", gorm.ErrRecordNotFound, ErrNotFound, CodeSubscriptionNotFound},
+		{"таймаут контекста", fmt.Errorf("timeout: %w", context.DeadlineExceeded), ErrTimeout, CodeRequestTimeout},
+		{"statement_timeout", &pgconn.PgError{Code: pgQueryCanceled}, ErrTimeout, CodeRequestTimeout},
+		{"отмена клиентом", fmt.Errorf("query: %w", context.Canceled), ErrCanceled, CodeRequestCanceled},
+		{"доменная ошибка", ErrVersionConflict, ErrConflict, CodeVersionConflict},
+		{"прочее", errors.New("connection refused"), ErrInternal, CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapRepoError(tt.err, "ошибка")
+
+			var domainErr *Error
+			if !errors.As(err, &domainErr) {
+				t.Fatalf("ожидали доменную ошибку, получили %v", err)
+			}
+			if !errors.Is(err, tt.wantKind) {
+				t.Errorf("категория = %v, ожидали %v", domainErr.Kind, tt.wantKind)
+			}
+			if domainErr.Code != tt.wantCode {
+				t.Errorf("код = %q, ожидали %q", domainErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
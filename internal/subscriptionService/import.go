@@ -0,0 +1,167 @@
+package subscriptionService
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxImportRows — максимальное число строк данных в одном CSV-файле
+const MaxImportRows = 10000
+
+// importColumns — колонки CSV; end_date и currency могут отсутствовать
+var importColumns = []string{"service_name", "price", "currency", "user_id", "start_date", "end_date"}
+
+var optionalImportColumns = map[string]bool{"end_date": true, "currency": true}
+
+type ImportResult struct {
+	DryRun    bool             `json:"dry_run"`
+	Committed bool             `json:"committed"`
+	TotalRows int              `json:"total_rows"`
+	ValidRows int              `json:"valid_rows"`
+	Imported  int              `json:"imported"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// ImportRowError — ошибка в строке CSV
+type ImportRowError struct {
+	Row     int    `json:"row"` // номер строки в файле, заголовок — строка 1
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ImportSubscriptions загружает подписки из CSV. Строки разбираются так же,
+// как тело POST /subscriptions. Если хотя бы одна строка содержит ошибку
+// или включен dryRun, в базу ничего не записывается; иначе все строки
+// сохраняются в одной транзакции.
+func (sub *subService) ImportSubscriptions(ctx context.Context, r io.Reader, dryRun bool) (ImportResult, error) {
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return ImportResult{}, newValidationError(CodeInvalidCSV, "не удалось прочитать заголовок CSV")
+	}
+
+	columns, err := importColumnIndexes(header)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+	var subscriptions []Subscription
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ImportResult{}, newValidationError(CodeInvalidCSV, "некорректный CSV: "+err.Error())
+		}
+
+		result.TotalRows++
+		if result.TotalRows > MaxImportRows {
+			return ImportResult{}, newValidationError(CodeInvalidCSV, "в файле больше "+strconv.Itoa(MaxImportRows)+" строк")
+		}
+
+		subscription, err := parseImportRecord(record, columns)
+		if err != nil {
+			rowErr := ImportRowError{Row: line, Code: CodeInvalidCSV, Message: err.Error()}
+			var domainErr *Error
+			if errors.As(err, &domainErr) {
+				rowErr.Code, rowErr.Message = domainErr.Code, domainErr.Message
+			}
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	result.ValidRows = len(subscriptions)
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	err = sub.repo.WithinTransaction(ctx, func(txRepo SubscriptionRepository) error {
+		for _, subscription := range subscriptions {
+			if _, err := txRepo.CreateSubscriptions(ctx, subscription); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, wrapRepoError(err, "не удалось сохранить подписки из CSV")
+	}
+
+	result.Committed = true
+	result.Imported = len(subscriptions)
+	return result, nil
+}
+
+// importColumnIndexes находит позиции известных колонок в заголовке
+func importColumnIndexes(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, name := range header {
+		// Excel любит добавлять BOM в начало файла
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok && !optionalImportColumns[name] {
+			return nil, newValidationError(CodeInvalidCSV, "в заголовке CSV нет колонки "+name)
+		}
+	}
+	return columns, nil
+}
+
+// parseImportRecord превращает строку CSV в подписку с проверками CreateSubscriptions
+func parseImportRecord(record []string, columns map[string]int) (Subscription, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	req := RequestBody{
+		ServiceName: field("service_name"),
+		Currency:    field("currency"),
+		StartDate:   field("start_date"),
+	}
+
+	if value := field("price"); value != "" {
+		price, err := strconv.Atoi(value)
+		if err != nil {
+			return Subscription{}, newValidationError(CodeInvalidPrice, "price должен быть целым числом")
+		}
+		req.Price = price
+	}
+
+	if value := field("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			return Subscription{}, newValidationError(CodeInvalidUserID, "невалидный UUID в user_id")
+		}
+		req.UserID = userID
+	}
+
+	if value := field("end_date"); value != "" {
+		req.EndDate = &value
+	}
+
+	if err := validateRequestBody(req); err != nil {
+		return Subscription{}, err
+	}
+	return applyRequestBody(Subscription{}, req)
+}
@@ -0,0 +1,354 @@
+package subscriptionService
+
+import (
+	"cmp"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RequestListParameters — параметры списка подписок в том виде, в каком они пришли в запросе
+type RequestListParameters struct {
+	Page              int
+	Limit             int
+	UserID            string
+	ServiceName       string
+	ServiceNamePrefix string
+	MinPrice          string
+	MaxPrice          string
+	ActiveAt          string // день или месяц, см. parseDate
+	StartFrom         string
+	StartTo           string
+	EndFrom           string
+	EndTo             string
+	Sort              string // например "-price,start_date"
+	CursorMode        bool   // пагинация курсором вместо page/limit
+	Cursor            string // непрозрачный курсор из next_cursor/prev_cursor
+}
+
+// ListFilter — проверенные параметры списка подписок
+type ListFilter struct {
+	Page              int
+	Limit             int
+	UserID            uuid.UUID
+	ServiceName       string
+	ServiceNamePrefix string
+	MinPrice          *int
+	MaxPrice          *int
+	ActiveAt          *DateRange
+	StartFrom         *time.Time
+	StartTo           *time.Time
+	EndFrom           *time.Time
+	EndTo             *time.Time
+	Sort              []SortField
+}
+
+// DateRange — интервал дат, обе границы включительно
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// SortField — одна колонка сортировки
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// sortableColumns — колонки, по которым разрешено сортировать список
+var sortableColumns = map[string]bool{
+	"id":           true,
+	"service_name": true,
+	"price":        true,
+	"user_id":      true,
+	"start_date":   true,
+	"end_date":     true,
+	"created_at":   true,
+	"updated_at":   true,
+}
+
+// parseListParameters проверяет параметры списка и возвращает фильтр
+// вместе с набором реально примененных фильтров для PaginationMeta.
+func parseListParameters(params RequestListParameters) (ListFilter, map[string]string, error) {
+	filter := ListFilter{
+		Page:              params.Page,
+		Limit:             params.Limit,
+		ServiceName:       params.ServiceName,
+		ServiceNamePrefix: params.ServiceNamePrefix,
+	}
+	applied := map[string]string{}
+
+	if params.UserID != "" {
+		userID, err := uuid.Parse(params.UserID)
+		if err != nil {
+			return ListFilter{}, nil, newValidationError(CodeInvalidUserID, "невалидный UUID в user_id")
+		}
+		filter.UserID = userID
+		applied["user_id"] = params.UserID
+	}
+	if params.ServiceName != "" {
+		applied["service_name"] = params.ServiceName
+	}
+	if params.ServiceNamePrefix != "" {
+		applied["service_name_prefix"] = params.ServiceNamePrefix
+	}
+
+	prices := []struct {
+		name  string
+		value string
+		dst   **int
+	}{
+		{"min_price", params.MinPrice, &filter.MinPrice},
+		{"max_price", params.MaxPrice, &filter.MaxPrice},
+	}
+	for _, p := range prices {
+		if p.value == "" {
+			continue
+		}
+		price, err := strconv.Atoi(p.value)
+		if err != nil {
+			return ListFilter{}, nil, newValidationError(CodeInvalidFilter, p.name+" должен быть целым числом")
+		}
+		*p.dst = &price
+		applied[p.name] = p.value
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return ListFilter{}, nil, newValidationError(CodeInvalidFilter, "min_price не может быть больше max_price")
+	}
+
+	// Месяц в active_at означает «действует хотя бы день этого месяца»,
+	// в верхних границах (*_to) — «не позже конца месяца»
+	if params.ActiveAt != "" {
+		from, err := parseDate(params.ActiveAt, false)
+		if err != nil {
+			return ListFilter{}, nil, newValidationError(CodeInvalidFilter, "active_at должен быть датой в формате "+DateFormatsHint)
+		}
+		to, _ := parseDate(params.ActiveAt, true)
+		filter.ActiveAt = &DateRange{From: from, To: to}
+		applied["active_at"] = params.ActiveAt
+	}
+
+	dates := []struct {
+		name       string
+		value      string
+		endOfMonth bool
+		dst        **time.Time
+	}{
+		{"start_from", params.StartFrom, false, &filter.StartFrom},
+		{"start_to", params.StartTo, true, &filter.StartTo},
+		{"end_from", params.EndFrom, false, &filter.EndFrom},
+		{"end_to", params.EndTo, true, &filter.EndTo},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		date, err := parseDate(d.value, d.endOfMonth)
+		if err != nil {
+			return ListFilter{}, nil, newValidationError(CodeInvalidFilter, d.name+" должен быть датой в формате "+DateFormatsHint)
+		}
+		*d.dst = &date
+		applied[d.name] = d.value
+	}
+
+	sort, err := parseSort(params.Sort)
+	if err != nil {
+		return ListFilter{}, nil, err
+	}
+	filter.Sort = sort
+
+	return filter, applied, nil
+}
+
+// parseSort разбирает строку вида "-price,start_date": минус означает убывание
+func parseSort(raw string) ([]SortField, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Column: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+
+		if !sortableColumns[field.Column] {
+			return nil, newValidationError(CodeInvalidSort, "сортировка по полю "+strconv.Quote(field.Column)+" не поддерживается")
+		}
+		if seen[field.Column] {
+			return nil, newValidationError(CodeInvalidSort, "поле "+strconv.Quote(field.Column)+" указано в сортировке дважды")
+		}
+		seen[field.Column] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// applyListFilter добавляет к запросу условия фильтра (без сортировки и пагинации)
+func applyListFilter(query *gorm.DB, filter ListFilter) *gorm.DB {
+	if filter.UserID != uuid.Nil {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+	if filter.ServiceNamePrefix != "" {
+		query = query.Where(`service_name LIKE ? ESCAPE '\'`, escapeLike(filter.ServiceNamePrefix)+"%")
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.ActiveAt != nil {
+		query = query.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", filter.ActiveAt.To, filter.ActiveAt.From)
+	}
+	if filter.StartFrom != nil {
+		query = query.Where("start_date >= ?", *filter.StartFrom)
+	}
+	if filter.StartTo != nil {
+		query = query.Where("start_date <= ?", *filter.StartTo)
+	}
+	if filter.EndFrom != nil {
+		query = query.Where("end_date >= ?", *filter.EndFrom)
+	}
+	if filter.EndTo != nil {
+		query = query.Where("end_date <= ?", *filter.EndTo)
+	}
+	return query
+}
+
+// matches проверяет подписку на условия фильтра — то же, что applyListFilter, в памяти
+func (filter ListFilter) matches(s Subscription) bool {
+	switch {
+	case filter.UserID != uuid.Nil && s.UserID != filter.UserID,
+		filter.ServiceName != "" && s.ServiceName != filter.ServiceName,
+		filter.ServiceNamePrefix != "" && !strings.HasPrefix(s.ServiceName, filter.ServiceNamePrefix),
+		filter.MinPrice != nil && s.Price < *filter.MinPrice,
+		filter.MaxPrice != nil && s.Price > *filter.MaxPrice,
+		filter.ActiveAt != nil && (s.StartDate.After(filter.ActiveAt.To) || (s.EndDate != nil && s.EndDate.Before(filter.ActiveAt.From))),
+		filter.StartFrom != nil && s.StartDate.Before(*filter.StartFrom),
+		filter.StartTo != nil && s.StartDate.After(*filter.StartTo),
+		// Как и в SQL, сравнение с NULL ложно: без end_date подписка не проходит
+		filter.EndFrom != nil && (s.EndDate == nil || s.EndDate.Before(*filter.EndFrom)),
+		filter.EndTo != nil && (s.EndDate == nil || s.EndDate.After(*filter.EndTo)):
+		return false
+	}
+	return true
+}
+
+// applySort добавляет сортировку; id в конце делает порядок однозначным.
+// Подписки без end_date идут последними при сортировке по возрастанию
+// и первыми по убыванию, как принято в Postgres, — в любой базе.
+func applySort(query *gorm.DB, sort []SortField) *gorm.DB {
+	hasID := false
+	for _, field := range sort {
+		// Имя колонки уже проверено по sortableColumns
+		switch {
+		case field.Column == "end_date" && field.Desc:
+			query = query.Order("end_date DESC NULLS FIRST")
+		case field.Column == "end_date":
+			query = query.Order("end_date NULLS LAST")
+		case field.Desc:
+			query = query.Order(field.Column + " DESC")
+		default:
+			query = query.Order(field.Column)
+		}
+		hasID = hasID || field.Column == "id"
+	}
+	if !hasID {
+		query = query.Order("id")
+	}
+	return query
+}
+
+// compareBySort сравнивает подписки в порядке applySort: результат меньше нуля, если a идет раньше b
+func compareBySort(a, b Subscription, sort []SortField) int {
+	for _, field := range sort {
+		result := compareColumn(a, b, field.Column)
+		if field.Desc {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+func compareColumn(a, b Subscription, column string) int {
+	switch column {
+	case "id":
+		return cmp.Compare(a.ID, b.ID)
+	case "service_name":
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	case "price":
+		return cmp.Compare(a.Price, b.Price)
+	case "user_id":
+		return strings.Compare(a.UserID.String(), b.UserID.String())
+	case "start_date":
+		return a.StartDate.Compare(b.StartDate)
+	case "end_date":
+		// NULL больше любой даты
+		switch {
+		case a.EndDate == nil && b.EndDate == nil:
+			return 0
+		case a.EndDate == nil:
+			return 1
+		case b.EndDate == nil:
+			return -1
+		}
+		return a.EndDate.Compare(*b.EndDate)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	return 0
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы префикс искался буквально
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// formatSort возвращает сортировку в том же виде, в каком её принимает API
+func formatSort(sort []SortField) string {
+	parts := make([]string, 0, len(sort))
+	for _, field := range sort {
+		if field.Desc {
+			parts = append(parts, "-"+field.Column)
+		} else {
+			parts = append(parts, field.Column)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ExportSubscriptions передает в fn все подписки, подходящие под фильтры списка,
+// без пагинации. Ошибка fn прерывает выгрузку и возвращается как есть.
+func (sub *subService) ExportSubscriptions(ctx context.Context, params RequestListParameters, fn func(Subscription) error) error {
+
+	filter, _, err := parseListParameters(params)
+	if err != nil {
+		return err
+	}
+
+	var callbackErr error
+	err = sub.repo.StreamSubscriptions(ctx, filter, func(s Subscription) error {
+		callbackErr = fn(s)
+		return callbackErr
+	})
+	if err != nil {
+		if callbackErr != nil {
+			return callbackErr
+		}
+		return wrapRepoError(err, "не удалось выгрузить подписки")
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package subscriptionService
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryRepository хранит подписки в памяти процесса. Подходит для тестов
+// сервиса и локального запуска без базы; поведение совпадает с subRepository,
+// что проверяется общим набором тестов repotest.
+type memoryRepository struct {
+	mu     sync.Mutex
+	subs   map[uint]Subscription
+	nextID uint
+}
+
+// NewMemoryRepository создает пустой репозиторий в памяти
+func NewMemoryRepository() SubscriptionRepository {
+	return &tracedRepository{next: newMemoryRepository()}
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{subs: map[uint]Subscription{}, nextID: 1}
+}
+
+// memoryNow — время записи; Postgres хранит микросекунды, округляем так же
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// copySubscription возвращает копию, не разделяющую end_date с хранилищем
+func copySubscription(s Subscription) Subscription {
+	if s.EndDate != nil {
+		end := *s.EndDate
+		s.EndDate = &end
+	}
+	return s
+}
+
+// alive возвращает неудаленные подписки, подходящие под условие, в порядке id
+func (r *memoryRepository) alive(match func(Subscription) bool) []Subscription {
+	subs := []Subscription{}
+	for _, s := range r.subs {
+		if !s.DeletedAt.Valid && match(s) {
+			subs = append(subs, copySubscription(s))
+		}
+	}
+	slices.SortFunc(subs, func(a, b Subscription) int { return compareBySort(a, b, nil) })
+	return subs
+}
+
+func notFound(id string) error {
+	return fmt.Errorf("подписка с ID %s не найдена: %w", id, gorm.ErrRecordNotFound)
+}
+
+// find ищет подписку по строковому ID; удаленные находятся, только если withDeleted
+func (r *memoryRepository) find(id string, withDeleted bool) (Subscription, bool) {
+	parsed, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return Subscription{}, false
+	}
+	s, ok := r.subs[uint(parsed)]
+	if !ok || (s.DeletedAt.Valid && !withDeleted) {
+		return Subscription{}, false
+	}
+	return s, true
+}
+
+func (r *memoryRepository) WithinTransaction(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Транзакции выполняются по одной: fn работает с копией, которая
+	// заменяет данные репозитория, только если fn завершилась без ошибки
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &memoryRepository{subs: make(map[uint]Subscription, len(r.subs)), nextID: r.nextID}
+	for id, s := range r.subs {
+		tx.subs[id] = copySubscription(s)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.subs, r.nextID = tx.subs, tx.nextID
+	return nil
+}
+
+func (r *memoryRepository) CreateSubscriptions(ctx context.Context, sub Subscription) (Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return Subscription{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := memoryNow()
+	sub.ID = r.nextID
+	sub.CreatedAt, sub.UpdatedAt = now, now
+	sub.DeletedAt = gorm.DeletedAt{}
+	if sub.Version == 0 {
+		sub.Version = 1
+	}
+	if sub.Currency == "" {
+		sub.Currency = DefaultCurrency
+	}
+	r.nextID++
+
+	r.subs[sub.ID] = copySubscription(sub)
+	return sub, nil
+}
+
+func (r *memoryRepository) ListSubscriptions(ctx context.Context, filter ListFilter) ([]Subscription, int64, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := r.alive(filter.matches)
+	slices.SortStableFunc(subs, func(a, b Subscription) int { return compareBySort(a, b, filter.Sort) })
+
+	totalItems := int64(len(subs))
+	totalPages := int(math.Ceil(float64(totalItems) / float64(filter.Limit)))
+
+	offset := min((filter.Page-1)*filter.Limit, len(subs))
+	end := min(offset+filter.Limit, len(subs))
+	return subs[offset:end], totalItems, totalPages, nil
+}
+
+func (r *memoryRepository) ListSubscriptionsByCursor(ctx context.Context, filter ListFilter, cursor *PageCursor, limit int) ([]Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byPosition := func(a, b Subscription) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return compareBySort(a, b, nil)
+	}
+	position := func(s Subscription) int {
+		return byPosition(s, Subscription{CreatedAt: cursor.CreatedAt, ID: cursor.ID})
+	}
+
+	var subs []Subscription
+	switch {
+	case cursor == nil:
+		subs = r.alive(filter.matches)
+		slices.SortFunc(subs, byPosition)
+	case cursor.Direction == CursorPrev:
+		subs = r.alive(func(s Subscription) bool { return filter.matches(s) && position(s) < 0 })
+		slices.SortFunc(subs, func(a, b Subscription) int { return byPosition(b, a) })
+	default:
+		subs = r.alive(func(s Subscription) bool { return filter.matches(s) && position(s) > 0 })
+		slices.SortFunc(subs, byPosition)
+	}
+
+	return subs[:min(limit, len(subs))], nil
+}
+
+func (r *memoryRepository) StreamSubscriptions(ctx context.Context, filter ListFilter, fn func(Subscription) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// fn вызывается без блокировки, чтобы она могла обращаться к репозиторию
+	r.mu.Lock()
+	subs := r.alive(filter.matches)
+	r.mu.Unlock()
+	slices.SortStableFunc(subs, func(a, b Subscription) int { return compareBySort(a, b, filter.Sort) })
+
+	for _, s := range subs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return Subscription{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.find(id, false)
+	if !ok {
+		return Subscription{}, gorm.ErrRecordNotFound
+	}
+	return copySubscription(s), nil
+}
+
+func (r *memoryRepository) UpdateSubcriptionByID(ctx context.Context, sub Subscription) (Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return Subscription{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := strconv.FormatUint(uint64(sub.ID), 10)
+	existing, ok := r.find(id, false)
+	if !ok {
+		return Subscription{}, notFound(id)
+	}
+	if existing.Version != sub.Version {
+		return Subscription{}, ErrVersionConflict
+	}
+
+	existing.ServiceName = sub.ServiceName
+	existing.Price = sub.Price
+	existing.Currency = sub.Currency
+	existing.UserID = sub.UserID
+	existing.StartDate = sub.StartDate
+	existing.EndDate = sub.EndDate
+	existing.Version++
+	existing.UpdatedAt = memoryNow()
+
+	r.subs[existing.ID] = copySubscription(existing)
+	return copySubscription(existing), nil
+}
+
+func (r *memoryRepository) DeleteSubcriptionByID(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.find(id, false)
+	if !ok {
+		return notFound(id)
+	}
+	s.DeletedAt = gorm.DeletedAt{Time: memoryNow(), Valid: true}
+	r.subs[s.ID] = s
+	return nil
+}
+
+func (r *memoryRepository) ListDeletedSubscriptions(ctx context.Context, page, limit int) ([]DeletedSubscription, int64, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := []DeletedSubscription{}
+	for _, s := range r.subs {
+		if s.DeletedAt.Valid {
+			deleted = append(deleted, DeletedSubscription{Subscription: copySubscription(s), DeletedAt: s.DeletedAt.Time})
+		}
+	}
+	// Как в subRepository: сначала недавно удаленные, затем по id
+	slices.SortFunc(deleted, func(a, b DeletedSubscription) int {
+		if c := b.DeletedAt.Compare(a.DeletedAt); c != 0 {
+			return c
+		}
+		return compareBySort(a.Subscription, b.Subscription, nil)
+	})
+
+	totalItems := int64(len(deleted))
+	totalPages := int(math.Ceil(float64(totalItems) / float64(limit)))
+
+	offset := min((page-1)*limit, len(deleted))
+	end := min(offset+limit, len(deleted))
+	return deleted[offset:end], totalItems, totalPages, nil
+}
+
+func (r *memoryRepository) RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return Subscription{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.find(id, true)
+	if !ok {
+		return Subscription{}, notFound(id)
+	}
+	if !s.DeletedAt.Valid {
+		return Subscription{}, ErrSubscriptionNotDeleted
+	}
+
+	s.DeletedAt = gorm.DeletedAt{}
+	s.Version++
+	s.UpdatedAt = memoryNow()
+	r.subs[s.ID] = s
+	return copySubscription(s), nil
+}
+
+func (r *memoryRepository) PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, s := range r.subs {
+		if s.DeletedAt.Valid && s.DeletedAt.Time.Before(deletedBefore) {
+			delete(r.subs, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// inPeriod возвращает неудаленные подписки, пересекающиеся с периодом
+func (r *memoryRepository) inPeriod(ctx context.Context, params ParametersСalculatingSum) ([]Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.alive(func(s Subscription) bool { return matchesPeriod(s, params) }), nil
+}
+
+func (r *memoryRepository) SumSubscriptionsPrice(ctx context.Context, params ParametersСalculatingSum, now time.Time) (int, error) {
+	subs, err := r.inPeriod(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	return calculateTotal(subs, params, now), nil
+}
+
+func (r *memoryRepository) MonthlyCostByGroup(ctx context.Context, params ParametersСalculatingSum, groupBy string, now time.Time) ([]MonthlyCostRow, error) {
+	if _, ok := groupKeyColumns[groupBy]; !ok {
+		return nil, fmt.Errorf("неизвестная группировка %q", groupBy)
+	}
+
+	subs, err := r.inPeriod(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return monthlyCostRows(subs, params, groupBy, now), nil
+}
+
+func (r *memoryRepository) ActiveSubscriptionStats(ctx context.Context, month time.Time) ([]ServiceStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type statsKey struct{ service, currency string }
+
+	stats := []ServiceStats{}
+	byKey := map[statsKey]int{}
+	for _, s := range r.alive(ListFilter{ActiveAt: activeInMonth(month)}.matches) {
+		key := statsKey{s.ServiceName, s.Currency}
+		i, ok := byKey[key]
+		if !ok {
+			i = len(stats)
+			byKey[key] = i
+			stats = append(stats, ServiceStats{ServiceName: s.ServiceName, Currency: s.Currency})
+		}
+		stats[i].ActiveSubscriptions++
+		stats[i].MonthlyRevenue += int64(s.Price)
+	}
+	slices.SortFunc(stats, func(a, b ServiceStats) int {
+		return cmp.Or(strings.Compare(a.ServiceName, b.ServiceName), strings.Compare(a.Currency, b.Currency))
+	})
+	return stats, nil
+}
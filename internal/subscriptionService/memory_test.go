@@ -0,0 +1,14 @@
+package subscriptionService_test
+
+import (
+	"testing"
+
+	"rest_service/internal/subscriptionService"
+	"rest_service/internal/subscriptionService/repotest"
+)
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) subscriptionService.SubscriptionRepository {
+		return subscriptionService.NewMemoryRepository()
+	})
+}
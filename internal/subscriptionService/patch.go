@@ -0,0 +1,104 @@
+package subscriptionService
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// PatchSubcriptionByID частично обновляет подписку по RFC 7396 (JSON Merge Patch).
+// Патч накладывается на текущее состояние подписки в формате RequestBody,
+// после чего результат проверяется так же, как тело PUT-запроса.
+func (sub *subService) PatchSubcriptionByID(ctx context.Context, patch []byte, id string, expectedVersions []int) (Subscription, error) {
+
+	var patchDoc map[string]interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil || patchDoc == nil {
+		return Subscription{}, newValidationError(CodeInvalidPatch, "merge-patch документ должен быть JSON-объектом")
+	}
+
+	existingSub, err := sub.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	if !versionMatches(expectedVersions, existingSub.Version) {
+		return Subscription{}, ErrVersionConflict
+	}
+
+	current, err := json.Marshal(toRequestBody(existingSub))
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	var currentDoc map[string]interface{}
+	if err := json.Unmarshal(current, &currentDoc); err != nil {
+		return Subscription{}, err
+	}
+
+	merged, err := json.Marshal(mergePatch(currentDoc, patchDoc))
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	// Неизвестные поля считаем ошибкой, чтобы опечатки не терялись молча
+	var req RequestBody
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return Subscription{}, newValidationError(CodeInvalidPatch, "некорректный merge-patch документ: "+err.Error())
+	}
+
+	if err := validateRequestBody(req); err != nil {
+		return Subscription{}, err
+	}
+
+	updatedSub, err := applyRequestBody(existingSub, req)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	savedSub, err := sub.repo.UpdateSubcriptionByID(ctx, updatedSub)
+	if err != nil {
+		return Subscription{}, wrapRepoError(err, "не удалось обновить подписку")
+	}
+	return savedSub, nil
+}
+
+// toRequestBody представляет подписку в том виде, в каком её присылает клиент
+func toRequestBody(s Subscription) RequestBody {
+	req := RequestBody{
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		Currency:    s.Currency,
+		UserID:      s.UserID,
+		StartDate:   formatDate(s.StartDate),
+	}
+	if s.EndDate != nil {
+		end := formatDate(*s.EndDate)
+		req.EndDate = &end
+	}
+	return req
+}
+
+// mergePatch применяет patch к target по алгоритму из RFC 7396:
+// null удаляет ключ, объекты сливаются рекурсивно, остальное заменяется целиком.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,34 @@
+package subscriptionService_test
+
+import (
+	"os"
+	"testing"
+
+	"rest_service/internal/pgtest"
+	"rest_service/internal/subscriptionService"
+	"rest_service/internal/subscriptionService/repotest"
+)
+
+// TestMain останавливает временный Postgres, если его поднял pgtest
+func TestMain(m *testing.M) {
+	code := m.Run()
+	pgtest.Stop()
+	os.Exit(code)
+}
+
+// Как и repository_test.go, идет на Postgres из pgtest. Каждый подтест работает
+// в своей транзакции, которая откатывается в конце.
+func TestPostgresRepositoryConformance(t *testing.T) {
+	db := pgtest.Open(t)
+
+	repotest.Run(t, func(t *testing.T) subscriptionService.SubscriptionRepository {
+		tx := db.Begin()
+		t.Cleanup(func() { tx.Rollback() })
+
+		// Тесты рассчитывают на пустую таблицу; очистка откатится вместе с транзакцией
+		if err := tx.Exec("DELETE FROM subscriptions").Error; err != nil {
+			t.Fatalf("не удалось очистить таблицу: %v", err)
+		}
+		return subscriptionService.NewSubscriptionRepository(tx)
+	})
+}
@@ -1,108 +1,301 @@
 package subscriptionService
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// SubscriptionRepository — хранилище подписок. Реализации: Postgres и SQLite
+// через gorm (NewSubscriptionRepository) и в памяти (NewMemoryRepository).
+// Все реализации обязаны проходить общий набор тестов из пакета repotest.
+//
+// Соглашения об ошибках, на которые опирается сервис:
+//   - отсутствующая (или удаленная) подписка — ошибка, для которой errors.Is(err, gorm.ErrRecordNotFound);
+//   - устаревшая версия при обновлении — ErrVersionConflict;
+//   - восстановление неудаленной подписки — ErrSubscriptionNotDeleted.
 type SubscriptionRepository interface {
-	ListSubscriptions(page, limit int) ([]Subscription, int64, int, error)
-	createSubscriptions(sub Subscription) (Subscription, error)
-	getSubscriptionByID(id string) (Subscription, error)
-	updateSubcriptionByID(sub Subscription) error
-	deleteSubcriptionByID(id string) error
-	getAmountOfSubscriptions(params ParametersСalculatingSum) ([]Subscription, error)
+	// ListSubscriptions возвращает страницу filter.Page размером filter.Limit,
+	// общее число подходящих подписок и число страниц
+	ListSubscriptions(ctx context.Context, filter ListFilter) ([]Subscription, int64, int, error)
+	// ListSubscriptionsByCursor возвращает до limit подписок после курсора в порядке
+	// (created_at, id), а для курсора назад — до него в обратном порядке
+	ListSubscriptionsByCursor(ctx context.Context, filter ListFilter, cursor *PageCursor, limit int) ([]Subscription, error)
+	// StreamSubscriptions передает в fn все подходящие подписки в порядке filter.Sort;
+	// ошибка fn прерывает чтение и возвращается как есть
+	StreamSubscriptions(ctx context.Context, filter ListFilter, fn func(Subscription) error) error
+	// CreateSubscriptions сохраняет новую подписку с версией 1
+	CreateSubscriptions(ctx context.Context, sub Subscription) (Subscription, error)
+	GetSubscriptionByID(ctx context.Context, id string) (Subscription, error)
+	// UpdateSubcriptionByID сохраняет подписку, если её версия не изменилась, и увеличивает версию
+	UpdateSubcriptionByID(ctx context.Context, sub Subscription) (Subscription, error)
+	// DeleteSubcriptionByID мягко удаляет подписку (переносит в корзину)
+	DeleteSubcriptionByID(ctx context.Context, id string) error
+	ListDeletedSubscriptions(ctx context.Context, page, limit int) ([]DeletedSubscription, int64, int, error)
+	RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error)
+	// PurgeDeletedSubscriptions окончательно удаляет подписки, удаленные раньше deletedBefore
+	PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// WithinTransaction выполняет fn атомарно: если fn вернула ошибку, изменения отменяются
+	WithinTransaction(ctx context.Context, fn func(repo SubscriptionRepository) error) error
+	// SumSubscriptionsPrice — сумма подписок за период, см. calculateTotal
+	SumSubscriptionsPrice(ctx context.Context, params ParametersСalculatingSum, now time.Time) (int, error)
+	// MonthlyCostByGroup — суммы по группе и месяцу, см. monthlyCostRows
+	MonthlyCostByGroup(ctx context.Context, params ParametersСalculatingSum, groupBy string, now time.Time) ([]MonthlyCostRow, error)
+	// ActiveSubscriptionStats — действующие в месяце подписки и их сумма по сервисам
+	ActiveSubscriptionStats(ctx context.Context, month time.Time) ([]ServiceStats, error)
 }
 
 type subRepository struct {
 	db *gorm.DB
 }
 
+// NewSubscriptionRepository создает репозиторий поверх gorm. Для Postgres суммы
+// считаются в SQL, для остальных диалектов (SQLite) — в Go по загруженным подпискам.
 func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
-	return &subRepository{db: db}
+	return &tracedRepository{next: &subRepository{db: db}}
 }
 
-func (r *subRepository) createSubscriptions(sub Subscription) (Subscription, error) {
+// isPostgres сообщает, можно ли использовать SQL, специфичный для Postgres
+func (r *subRepository) isPostgres() bool {
+	return r.db.Dialector.Name() == "postgres"
+}
 
-	r.db = r.db.Debug()
+// WithinTransaction выполняет fn с репозиторием, работающим внутри одной транзакции.
+// Если fn вернула ошибку, транзакция откатывается.
+func (r *subRepository) WithinTransaction(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&subRepository{db: tx})
+	})
+}
 
-	if err := r.db.Create(&sub).Error; err != nil {
-		log.Printf("Ошибка создания подписки: %v", err)
+func (r *subRepository) CreateSubscriptions(ctx context.Context, sub Subscription) (Subscription, error) {
+	if err := r.db.WithContext(ctx).Create(&sub).Error; err != nil {
 		return Subscription{}, err
 	}
-	log.Printf("Сохранено: %+v", sub)
 	return sub, nil
 }
 
-func (r *subRepository) ListSubscriptions(page, limit int) ([]Subscription, int64, int, error) {
+func (r *subRepository) ListSubscriptions(ctx context.Context, filter ListFilter) ([]Subscription, int64, int, error) {
+	db := r.db.WithContext(ctx)
+
 	var subs []Subscription
 
-	offset := (page - 1) * limit
+	offset := (filter.Page - 1) * filter.Limit
 
-	err := r.db.Offset(offset).Limit(limit).Find(&subs).Error
+	query := applyListFilter(db.Model(&Subscription{}), filter)
+	err := applySort(query.Session(&gorm.Session{}), filter.Sort).
+		Offset(offset).Limit(filter.Limit).Find(&subs).Error
 
 	var totalItems int64
-	if err := r.db.Model(&Subscription{}).Count(&totalItems).Error; err != nil {
+	if err := query.Count(&totalItems).Error; err != nil {
 		return nil, 0, 0, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(limit)))
+	totalPages := int(math.Ceil(float64(totalItems) / float64(filter.Limit)))
 
 	return subs, totalItems, totalPages, err
 }
 
-func (r *subRepository) getSubscriptionByID(id string) (Subscription, error) {
+// ListSubscriptionsByCursor возвращает до limit подписок после позиции курсора
+// в порядке (created_at, id), а для курсора назад — до неё в обратном порядке.
+// В отличие от OFFSET, вставка новых строк не сдвигает уже просмотренные.
+func (r *subRepository) ListSubscriptionsByCursor(ctx context.Context, filter ListFilter, cursor *PageCursor, limit int) ([]Subscription, error) {
+	db := r.db.WithContext(ctx)
+
+	query := applyListFilter(db.Model(&Subscription{}), filter)
+
+	switch {
+	case cursor == nil:
+		query = query.Order("created_at, id")
+	case cursor.Direction == CursorPrev:
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID).
+			Order("created_at DESC, id DESC")
+	default:
+		query = query.Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID).
+			Order("created_at, id")
+	}
+
+	var subs []Subscription
+	if err := query.Limit(limit).Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// StreamSubscriptions построчно читает все подписки, подходящие под фильтр,
+// и передает их в fn, не загружая результат в память целиком.
+func (r *subRepository) StreamSubscriptions(ctx context.Context, filter ListFilter, fn func(Subscription) error) error {
+	db := r.db.WithContext(ctx)
+
+	query := applySort(applyListFilter(db.Model(&Subscription{}), filter), filter.Sort)
+
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sub Subscription
+		if err := db.ScanRows(rows, &sub); err != nil {
+			return err
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *subRepository) GetSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	db := r.db.WithContext(ctx)
+
 	var sub Subscription
-	err := r.db.First(&sub, "id = ?", id).Error
+	err := db.First(&sub, "id = ?", id).Error
 	return sub, err
 }
 
-func (r *subRepository) updateSubcriptionByID(sub Subscription) error {
-	var existingSub Subscription
-	result := r.db.First(&existingSub, "id = ?", sub.ID)
+// UpdateSubcriptionByID сохраняет подписку, только если её версия в базе
+// совпадает с sub.Version, и увеличивает версию на единицу.
+func (r *subRepository) UpdateSubcriptionByID(ctx context.Context, sub Subscription) (Subscription, error) {
+	db := r.db.WithContext(ctx)
 
+	result := db.Model(&Subscription{ID: sub.ID}).
+		Where("version = ?", sub.Version).
+		Updates(map[string]interface{}{
+			"service_name": sub.ServiceName,
+			"price":        sub.Price,
+			"currency":     sub.Currency,
+			"user_id":      sub.UserID,
+			"start_date":   sub.StartDate,
+			"end_date":     sub.EndDate,
+			"version":      gorm.Expr("version + 1"),
+		})
 	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("подписка с ID %d не найдена", sub.ID)
+		return Subscription{}, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		// Либо подписку удалили, либо её успели изменить
+		var existingSub Subscription
+		if err := db.First(&existingSub, "id = ?", sub.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return Subscription{}, fmt.Errorf("подписка с ID %d не найдена: %w", sub.ID, err)
+			}
+			return Subscription{}, err
 		}
-		return result.Error
+		return Subscription{}, ErrVersionConflict
 	}
 
-	err := r.db.Save(&sub).Error
-	return err
+	var updatedSub Subscription
+	if err := db.First(&updatedSub, "id = ?", sub.ID).Error; err != nil {
+		return Subscription{}, err
+	}
+	return updatedSub, nil
 }
 
-func (r *subRepository) deleteSubcriptionByID(id string) error {
+func (r *subRepository) DeleteSubcriptionByID(ctx context.Context, id string) error {
+	db := r.db.WithContext(ctx)
+
 	var sub Subscription
-	result := r.db.First(&sub, "id = ?", id)
+	result := db.First(&sub, "id = ?", id)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("подписка с ID %s не найдена", id)
+			return fmt.Errorf("подписка с ID %s не найдена: %w", id, result.Error)
 		}
 		return result.Error
 	}
 
 	// Удаляем только если подписка существует
-	result = r.db.Delete(&Subscription{}, "id = ?", id)
+	result = db.Delete(&Subscription{}, "id = ?", id)
 	if result.Error != nil {
-		log.Printf("Ошибка удаления подписки ID %s: %v", id, result.Error)
 		return result.Error
 	}
 
 	return nil
 }
 
-func (r *subRepository) getAmountOfSubscriptions(params ParametersСalculatingSum) ([]Subscription, error) {
+// ListDeletedSubscriptions возвращает мягко удаленные подписки, сначала недавние
+func (r *subRepository) ListDeletedSubscriptions(ctx context.Context, page, limit int) ([]DeletedSubscription, int64, int, error) {
+	db := r.db.WithContext(ctx)
+
+	query := db.Unscoped().Model(&Subscription{}).Where("deleted_at IS NOT NULL")
+
+	var subs []Subscription
+	err := query.Session(&gorm.Session{}).
+		Order("deleted_at DESC, id").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&subs).Error
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	deleted := make([]DeletedSubscription, 0, len(subs))
+	for _, sub := range subs {
+		deleted = append(deleted, DeletedSubscription{Subscription: sub, DeletedAt: sub.DeletedAt.Time})
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(limit)))
+
+	return deleted, totalItems, totalPages, nil
+}
+
+// RestoreSubscriptionByID снимает пометку об удалении и увеличивает версию подписки
+func (r *subRepository) RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	db := r.db.WithContext(ctx)
+
+	result := db.Unscoped().Model(&Subscription{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return Subscription{}, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		// Либо подписки нет совсем, либо она не удалена
+		var existingSub Subscription
+		if err := db.Unscoped().First(&existingSub, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return Subscription{}, fmt.Errorf("подписка с ID %s не найдена: %w", id, err)
+			}
+			return Subscription{}, err
+		}
+		return Subscription{}, ErrSubscriptionNotDeleted
+	}
+
+	var restoredSub Subscription
+	if err := db.First(&restoredSub, "id = ?", id).Error; err != nil {
+		return Subscription{}, err
+	}
+	return restoredSub, nil
+}
+
+// PurgeDeletedSubscriptions физически удаляет подписки, удаленные раньше deletedBefore
+func (r *subRepository) PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	db := r.db.WithContext(ctx)
 
-	query := r.db.Model(&Subscription{})
+	result := db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Delete(&Subscription{})
+	return result.RowsAffected, result.Error
+}
 
-	// Фильтрации
+// filterForPeriod оставляет подписки, пересекающиеся с периодом, с учетом фильтров
+func filterForPeriod(query *gorm.DB, params ParametersСalculatingSum) *gorm.DB {
 	query = query.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", params.EndDate, params.StartDate)
 
 	if params.UserID != uuid.Nil {
@@ -111,6 +304,31 @@ func (r *subRepository) getAmountOfSubscriptions(params ParametersСalculatingSu
 	if params.ServiceName != "" {
 		query = query.Where("service_name = ?", params.ServiceName)
 	}
+	if params.Currency != "" {
+		query = query.Where("currency = ?", params.Currency)
+	}
+	return query
+}
+
+// matchesPeriod — то же, что filterForPeriod, для одной подписки в памяти
+func matchesPeriod(s Subscription, params ParametersСalculatingSum) bool {
+	switch {
+	case s.StartDate.After(params.EndDate),
+		s.EndDate != nil && s.EndDate.Before(params.StartDate),
+		params.UserID != uuid.Nil && s.UserID != params.UserID,
+		params.ServiceName != "" && s.ServiceName != params.ServiceName,
+		params.Currency != "" && s.Currency != params.Currency:
+		return false
+	}
+	return true
+}
+
+// getAmountOfSubscriptions загружает все подписки, попадающие в период.
+// Используется только как эталон для проверки SumSubscriptionsPrice.
+func (r *subRepository) getAmountOfSubscriptions(ctx context.Context, params ParametersСalculatingSum) ([]Subscription, error) {
+	db := r.db.WithContext(ctx)
+
+	query := filterForPeriod(db.Model(&Subscription{}), params)
 
 	var subscriptions []Subscription
 	if err := query.Find(&subscriptions).Error; err != nil {
@@ -119,3 +337,171 @@ func (r *subRepository) getAmountOfSubscriptions(params ParametersСalculatingSu
 
 	return subscriptions, nil
 }
+
+// monthDiffSQL — число месяцев от billing_start до даты x; chargeDaySQL —
+// день списания в месяце даты x. Это monthDiff и chargeDay из chargeRange.
+func monthDiffSQL(x string) string {
+	return "((EXTRACT(YEAR FROM " + x + ") - EXTRACT(YEAR FROM billing_start)) * 12" +
+		" + EXTRACT(MONTH FROM " + x + ") - EXTRACT(MONTH FROM billing_start))"
+}
+
+func chargeDaySQL(x string) string {
+	return "LEAST(EXTRACT(DAY FROM billing_start), EXTRACT(DAY FROM date_trunc('month', " + x + "::timestamp) + interval '1 month - 1 day'))"
+}
+
+// periodBounds возвращает подзапрос с ценой и днями пересечения каждой
+// подписки с периодом (period_start, period_end).
+// Незавершенные подписки считаются действующими до now.
+//
+// start_date и end_date хранятся как date; параметры pgx переводит в date по
+// календарному дню значения, поэтому они передаются в UTC, как их считает parseDate.
+func periodBounds(query *gorm.DB, params ParametersСalculatingSum, now time.Time) *gorm.DB {
+	return filterForPeriod(query.Model(&Subscription{}), params).
+		Select(`price, service_name, user_id,
+			start_date AS billing_start,
+			GREATEST(start_date, ?::date) AS period_start,
+			LEAST(COALESCE(end_date, ?::date), ?::date) AS period_end`,
+			params.StartDate.UTC(), now.UTC(), params.EndDate.UTC())
+}
+
+// periodsForSum дополняет periodBounds номерами первого и последнего
+// списания подписки внутри периода (first_charge, last_charge), как их
+// считает chargeRange.
+func periodsForSum(query *gorm.DB, params ParametersСalculatingSum, now time.Time) *gorm.DB {
+	return query.Table("(?) AS bounds", periodBounds(query, params, now)).
+		Select(`price, service_name, user_id, billing_start,
+			GREATEST(0, ` + monthDiffSQL("period_start") + `
+				+ CASE WHEN ` + chargeDaySQL("period_start") + ` < EXTRACT(DAY FROM period_start) THEN 1 ELSE 0 END)::int AS first_charge,
+			(` + monthDiffSQL("period_end") + `
+				- CASE WHEN ` + chargeDaySQL("period_end") + ` > EXTRACT(DAY FROM period_end) THEN 1 ELSE 0 END)::int AS last_charge`)
+}
+
+// proratedCostSQL — доля цены за месяц months.month: price × дни действия в
+// месяце / дней в месяце, с округлением половины вверх, как prorate
+const proratedCostSQL = `(periods.price::bigint * 2 * GREATEST(0,
+		LEAST(periods.period_end, (months.month + interval '1 month - 1 day')::date)
+		- GREATEST(periods.period_start, months.month::date) + 1)
+	+ EXTRACT(DAY FROM months.month + interval '1 month - 1 day')::int)
+	/ (2 * EXTRACT(DAY FROM months.month + interval '1 month - 1 day')::int)`
+
+// monthlyCosts возвращает запрос, в котором каждой подписке сопоставлены
+// месяцы (months.month), и выражение стоимости подписки в таком месяце:
+// для CalculationMonthly — месяцы списаний и полная цена, для
+// CalculationProrated — все календарные месяцы пересечения и доля цены.
+func monthlyCosts(db *gorm.DB, params ParametersСalculatingSum, now time.Time) (*gorm.DB, string) {
+	if params.Mode == CalculationProrated {
+		query := db.Table("(?) AS periods", periodBounds(db, params, now)).
+			Joins(`CROSS JOIN LATERAL generate_series(
+				date_trunc('month', periods.period_start::timestamp),
+				date_trunc('month', periods.period_end::timestamp),
+				interval '1 month') AS months(month)`)
+		return query, proratedCostSQL
+	}
+
+	query := db.Table("(?) AS periods", periodsForSum(db, params, now)).
+		Joins(`CROSS JOIN LATERAL (
+			SELECT date_trunc('month', periods.billing_start + charge * interval '1 month') AS month
+			FROM generate_series(periods.first_charge, periods.last_charge) AS charge
+		) AS months`)
+	return query, "periods.price"
+}
+
+// SumSubscriptionsPrice считает сумму подписок за период одним запросом.
+// В режиме CalculationMonthly подписка списывается в день начала и далее
+// ежемесячно в тот же день, каждое списание внутри периода (включительно)
+// добавляет цену. В режиме CalculationProrated складываются доли цены по
+// дням действия в каждом месяце. Даты берутся в UTC, как и в calculateTotal.
+func (r *subRepository) SumSubscriptionsPrice(ctx context.Context, params ParametersСalculatingSum, now time.Time) (int, error) {
+	if !r.isPostgres() {
+		subs, err := r.getAmountOfSubscriptions(ctx, params)
+		if err != nil {
+			return 0, err
+		}
+		return calculateTotal(subs, params, now), nil
+	}
+
+	db := r.db.WithContext(ctx)
+
+	var total int64
+	var err error
+	if params.Mode == CalculationProrated {
+		query, cost := monthlyCosts(db, params, now)
+		err = query.Select("COALESCE(SUM(" + cost + "), 0)::bigint").Scan(&total).Error
+	} else {
+		err = db.Table("(?) AS periods", periodsForSum(db, params, now)).
+			Select("COALESCE(SUM(price * GREATEST(0, last_charge - first_charge + 1)), 0)::bigint").
+			Scan(&total).Error
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return int(total), nil
+}
+
+// MonthlyCostRow — сумма подписок группы за один месяц
+type MonthlyCostRow struct {
+	GroupKey string
+	Month    time.Time
+	Total    int64
+}
+
+// groupKeyColumns — допустимые группировки и соответствующие им выражения SQL
+var groupKeyColumns = map[string]string{
+	GroupByServiceName: "periods.service_name",
+	GroupByUserID:      "periods.user_id::text",
+	GroupByMonth:       "to_char(months.month, 'MM-YYYY')",
+}
+
+// MonthlyCostByGroup раскладывает стоимость каждой подписки за период по
+// месяцам (monthlyCosts) и суммирует по группе и месяцу.
+func (r *subRepository) MonthlyCostByGroup(ctx context.Context, params ParametersСalculatingSum, groupBy string, now time.Time) ([]MonthlyCostRow, error) {
+	db := r.db.WithContext(ctx)
+
+	keyColumn, ok := groupKeyColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("неизвестная группировка %q", groupBy)
+	}
+
+	if !r.isPostgres() {
+		subs, err := r.getAmountOfSubscriptions(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return monthlyCostRows(subs, params, groupBy, now), nil
+	}
+
+	// Для помесячной группировки ключ — строка MM-YYYY, сортировать по ней нельзя
+	order := "group_key, month"
+	if groupBy == GroupByMonth {
+		order = "month"
+	}
+
+	query, cost := monthlyCosts(db, params, now)
+
+	var rows []MonthlyCostRow
+	err := query.
+		Select(keyColumn + " AS group_key, months.month AS month, SUM(" + cost + ")::bigint AS total").
+		Group("group_key, months.month").
+		Order(order).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// ActiveSubscriptionStats считает действующие в месяце подписки и их сумму
+// по сервисам и валютам
+func (r *subRepository) ActiveSubscriptionStats(ctx context.Context, month time.Time) ([]ServiceStats, error) {
+	db := r.db.WithContext(ctx)
+
+	var stats []ServiceStats
+	err := applyListFilter(db.Model(&Subscription{}), ListFilter{ActiveAt: activeInMonth(month)}).
+		Select("service_name, currency, COUNT(*) AS active_subscriptions, COALESCE(SUM(price), 0) AS monthly_revenue").
+		Group("service_name, currency").
+		Order("service_name, currency").
+		Scan(&stats).Error
+	return stats, err
+}
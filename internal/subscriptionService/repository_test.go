@@ -0,0 +1,256 @@
+package subscriptionService
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"rest_service/internal/pgtest"
+
+	"github.com/google/uuid"
+)
+
+// Тесты этого файла идут на настоящем Postgres: TEST_DATABASE_DSN или
+// временный локальный кластер (см. pgtest). Все изменения делаются внутри
+// транзакции и откатываются в конце.
+func openTestRepository(tb testing.TB) *subRepository {
+	tb.Helper()
+
+	tx := pgtest.Open(tb).Begin()
+	tb.Cleanup(func() { tx.Rollback() })
+
+	return &subRepository{db: tx}
+}
+
+// seedSubscriptions создает n случайных подписок за 2020–2027 годы с началом
+// и окончанием в любой день месяца (треть — бессрочные) и boundarySubscriptions
+func seedSubscriptions(tb testing.TB, repo *subRepository, n int) []uuid.UUID {
+	tb.Helper()
+
+	rnd := rand.New(rand.NewSource(42))
+	services := []string{"Yandex Plus", "Netflix", "Spotify", "Kinopoisk"}
+	users := make([]uuid.UUID, 20)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	subs := make([]Subscription, 0, n)
+	for i := 0; i < n; i++ {
+		// День до 31: в коротких месяцах переносится на следующий месяц, как AddDate
+		start := time.Date(2020+rnd.Intn(8), time.Month(1+rnd.Intn(12)), 1+rnd.Intn(31), 0, 0, 0, 0, time.UTC)
+		sub := Subscription{
+			ServiceName: services[rnd.Intn(len(services))],
+			Price:       100 + rnd.Intn(900),
+			UserID:      users[rnd.Intn(len(users))],
+			StartDate:   start,
+		}
+		if rnd.Intn(3) > 0 {
+			end := start.AddDate(0, rnd.Intn(36), rnd.Intn(31))
+			sub.EndDate = &end
+		}
+		subs = append(subs, sub)
+	}
+	subs = append(subs, boundarySubscriptions(users[0])...)
+
+	if err := repo.db.CreateInBatches(subs, 500).Error; err != nil {
+		tb.Fatalf("не удалось создать подписки: %v", err)
+	}
+	return users
+}
+
+// boundarySubscriptions — подписки на границах периодов из sumParams и месяцев:
+// начало и конец ровно в день границы, за день до и после нее, 31-е и 29 февраля
+func boundarySubscriptions(user uuid.UUID) []Subscription {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	datePtr := func(year int, month time.Month, day int) *time.Time {
+		d := date(year, month, day)
+		return &d
+	}
+	return []Subscription{
+		{ServiceName: "Netflix", Price: 301, UserID: user, StartDate: date(2023, 5, 1), EndDate: datePtr(2024, 2, 29)},
+		{ServiceName: "Netflix", Price: 302, UserID: user, StartDate: date(2023, 4, 30), EndDate: datePtr(2023, 5, 1)},
+		{ServiceName: "Netflix", Price: 303, UserID: user, StartDate: date(2024, 2, 29), EndDate: datePtr(2024, 3, 1)},
+		{ServiceName: "Netflix", Price: 304, UserID: user, StartDate: date(2024, 3, 1)},
+		{ServiceName: "Netflix", Price: 305, UserID: user, StartDate: date(2023, 4, 29), EndDate: datePtr(2023, 4, 30)},
+		{ServiceName: "Spotify", Price: 306, UserID: user, StartDate: date(2024, 1, 31)},
+		{ServiceName: "Spotify", Price: 307, UserID: user, StartDate: date(2024, 2, 29), EndDate: datePtr(2025, 3, 28)},
+		{ServiceName: "Spotify", Price: 308, UserID: user, StartDate: date(2025, 1, 15), EndDate: datePtr(2025, 1, 15)},
+		{ServiceName: "Kinopoisk", Price: 309, UserID: user, StartDate: date(2024, 12, 31), EndDate: datePtr(2025, 1, 2)},
+		{ServiceName: "Kinopoisk", Price: 310, UserID: user, StartDate: date(2026, 6, 15)},
+		{ServiceName: "Kinopoisk", Price: 311, UserID: user, StartDate: date(2026, 6, 16)},
+	}
+}
+
+// sumParams — периоды для сравнения SQL с Go: целые месяцы, середины месяцев,
+// периоды с границей в 29 февраля и в текущем дне, в обоих режимах расчета
+func sumParams(users []uuid.UUID) []ParametersСalculatingSum {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	periods := []ParametersСalculatingSum{
+		{StartDate: date(2020, 1, 1), EndDate: date(2030, 12, 31)},
+		{StartDate: date(2023, 5, 1), EndDate: date(2024, 2, 29)},
+		{StartDate: date(2025, 1, 1), EndDate: date(2025, 1, 31), ServiceName: "Netflix"},
+		{StartDate: date(2021, 3, 1), EndDate: date(2027, 9, 30), UserID: users[0]},
+		{StartDate: date(2023, 4, 30), EndDate: date(2023, 5, 1)},
+		{StartDate: date(2024, 1, 15), EndDate: date(2024, 3, 14)},
+		{StartDate: date(2024, 2, 29), EndDate: date(2024, 2, 29)},
+		{StartDate: date(2024, 12, 31), EndDate: date(2025, 1, 2), UserID: users[0]},
+		{StartDate: date(2026, 6, 1), EndDate: date(2026, 6, 30)},
+	}
+
+	params := make([]ParametersСalculatingSum, 0, 2*len(periods))
+	for _, mode := range []string{CalculationMonthly, CalculationProrated} {
+		for _, p := range periods {
+			p.Currency = DefaultCurrency
+			p.Mode = mode
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// TestSumSubscriptionsPriceMatchesGoCalculation сверяет SQL-агрегат с
+// calculateTotal — расчетом в Go со списанием в день начала каждый месяц
+// (chargeRange) или по дням (proratedCosts), а не с прежним monthsBetween.
+// Ожидаемые суммы на граничных подписках закреплены отдельно в repotest
+// (BoundarySums), который идет и без Postgres: на памяти и SQLite.
+func TestSumSubscriptionsPriceMatchesGoCalculation(t *testing.T) {
+	repo := openTestRepository(t)
+	users := seedSubscriptions(t, repo, 2000)
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	for _, params := range sumParams(users) {
+		subs, err := repo.getAmountOfSubscriptions(ctx, params)
+		if err != nil {
+			t.Fatalf("getAmountOfSubscriptions: %v", err)
+		}
+		want := calculateTotal(subs, params, now)
+
+		got, err := repo.SumSubscriptionsPrice(ctx, params, now)
+		if err != nil {
+			t.Fatalf("SumSubscriptionsPrice: %v", err)
+		}
+		if got != want {
+			t.Errorf("params %+v: SQL = %d, Go = %d", params, got, want)
+		}
+	}
+}
+
+// BenchmarkSumSubscriptionsPrice сравнивает расчет суммы в Go (загрузка всех
+// подписок периода и calculateTotal) с одним SQL-запросом. Перед замером
+// проверяется, что оба пути дают одну и ту же сумму.
+func BenchmarkSumSubscriptionsPrice(b *testing.B) {
+	repo := openTestRepository(b)
+	users := seedSubscriptions(b, repo, 20000)
+	now := time.Now().UTC()
+	ctx := context.Background()
+
+	for _, mode := range []string{CalculationMonthly, CalculationProrated} {
+		params := sumParams(users)[0]
+		params.Mode = mode
+
+		subs, err := repo.getAmountOfSubscriptions(ctx, params)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sqlTotal, err := repo.SumSubscriptionsPrice(ctx, params, now)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if goTotal := calculateTotal(subs, params, now); sqlTotal != goTotal {
+			b.Fatalf("%s: SQL = %d, Go = %d", mode, sqlTotal, goTotal)
+		}
+
+		b.Run(mode+"/go", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				subs, err := repo.getAmountOfSubscriptions(ctx, params)
+				if err != nil {
+					b.Fatal(err)
+				}
+				calculateTotal(subs, params, now)
+			}
+		})
+
+		b.Run(mode+"/sql", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.SumSubscriptionsPrice(ctx, params, now); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMonthlyCostByGroupMatchesTotal(t *testing.T) {
+	repo := openTestRepository(t)
+	users := seedSubscriptions(t, repo, 2000)
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	for _, params := range sumParams(users) {
+		want, err := repo.SumSubscriptionsPrice(ctx, params, now)
+		if err != nil {
+			t.Fatalf("SumSubscriptionsPrice: %v", err)
+		}
+
+		for _, groupBy := range []string{GroupByServiceName, GroupByUserID, GroupByMonth} {
+			rows, err := repo.MonthlyCostByGroup(ctx, params, groupBy, now)
+			if err != nil {
+				t.Fatalf("MonthlyCostByGroup(%s): %v", groupBy, err)
+			}
+			if got := buildBreakdown(rows, groupBy, params).TotalPrice; got != want {
+				t.Errorf("group_by=%s, params %+v: по группам %d, всего %d", groupBy, params, got, want)
+			}
+		}
+	}
+}
+
+// TestProratedCostsSQL прогоняет таблицу proratedCases через proratedCostSQL:
+// помесячные суммы (MonthlyCostByGroup) и итог (SumSubscriptionsPrice)
+// должны совпасть с prorate в Go
+func TestProratedCostsSQL(t *testing.T) {
+	pgtest.Open(t) // без базы пропускаем тест целиком, а не каждый случай
+	ctx := context.Background()
+
+	for _, tt := range proratedCases {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := openTestRepository(t)
+			sub := tt.sub
+			sub.ServiceName = "Netflix"
+			sub.UserID = uuid.New()
+			if _, err := repo.CreateSubscriptions(ctx, sub); err != nil {
+				t.Fatalf("CreateSubscriptions: %v", err)
+			}
+
+			rows, err := repo.MonthlyCostByGroup(ctx, proratedPeriod, GroupByMonth, proratedNow)
+			if err != nil {
+				t.Fatalf("MonthlyCostByGroup: %v", err)
+			}
+			got := make([]monthCost, 0, len(rows))
+			want := 0
+			for _, row := range rows {
+				got = append(got, monthCost{month: row.Month.UTC(), amount: int(row.Total)})
+			}
+			for _, cost := range tt.want {
+				want += cost.amount
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("по месяцам: SQL %v, ожидали %v", got, tt.want)
+			}
+
+			total, err := repo.SumSubscriptionsPrice(ctx, proratedPeriod, proratedNow)
+			if err != nil {
+				t.Fatalf("SumSubscriptionsPrice: %v", err)
+			}
+			if total != want {
+				t.Errorf("сумма: SQL %d, ожидали %d", total, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,841 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"rest_service/internal/subscriptionService"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Factory создает пустой репозиторий для одного теста
+type Factory func(t *testing.T) subscriptionService.SubscriptionRepository
+
+// Run проверяет, что реализация репозитория соблюдает контракт
+// SubscriptionRepository. Каждый подтест получает новый пустой репозиторий.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo subscriptionService.SubscriptionRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"GetMissing", testGetMissing},
+		{"UpdateChecksVersion", testUpdateChecksVersion},
+		{"DeleteAndRestore", testDeleteAndRestore},
+		{"PurgeDeleted", testPurgeDeleted},
+		{"ListFilters", testListFilters},
+		{"ListSortAndPages", testListSortAndPages},
+		{"Cursor", testCursor},
+		{"Stream", testStream},
+		{"Transaction", testTransaction},
+		{"Sums", testSums},
+		{"MonthlyCostByGroup", testMonthlyCostByGroup},
+		{"ActiveSubscriptionStats", testActiveSubscriptionStats},
+		{"Currencies", testCurrencies},
+		{"BillingDay", testBillingDay},
+		{"BoundarySums", testBoundarySums},
+		{"Prorated", testProrated},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+var (
+	userA = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+	userB = uuid.MustParse("22222222-2222-4222-8222-222222222222")
+)
+
+// month — первое число месяца в UTC
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthPtr(year int, m time.Month) *time.Time {
+	date := month(year, m)
+	return &date
+}
+
+func day(year int, m time.Month, d int) time.Time {
+	return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func dayPtr(year int, m time.Month, d int) *time.Time {
+	date := day(year, m, d)
+	return &date
+}
+
+func create(t *testing.T, repo subscriptionService.SubscriptionRepository, sub subscriptionService.Subscription) subscriptionService.Subscription {
+	t.Helper()
+	created, err := repo.CreateSubscriptions(context.Background(), sub)
+	if err != nil {
+		t.Fatalf("CreateSubscriptions: %v", err)
+	}
+	return created
+}
+
+// seed создает пять подписок; порядок создания совпадает с порядком id:
+//
+//	0: Netflix      500 userA 01-2024 – 06-2024
+//	1: Netflix      300 userB 03-2024 – …
+//	2: Spotify      200 userA 11-2023 – 02-2024
+//	3: Yandex Plus  400 userB 05-2024 – 01-2025
+//	4: yandex music 100 userA 02-2025 – …
+func seed(t *testing.T, repo subscriptionService.SubscriptionRepository) []subscriptionService.Subscription {
+	t.Helper()
+	subs := []subscriptionService.Subscription{
+		{ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1), EndDate: monthPtr(2024, 6)},
+		{ServiceName: "Netflix", Price: 300, UserID: userB, StartDate: month(2024, 3)},
+		{ServiceName: "Spotify", Price: 200, UserID: userA, StartDate: month(2023, 11), EndDate: monthPtr(2024, 2)},
+		{ServiceName: "Yandex Plus", Price: 400, UserID: userB, StartDate: month(2024, 5), EndDate: monthPtr(2025, 1)},
+		{ServiceName: "yandex music", Price: 100, UserID: userA, StartDate: month(2025, 2)},
+	}
+	for i := range subs {
+		subs[i] = create(t, repo, subs[i])
+	}
+	return subs
+}
+
+func ids(subs []subscriptionService.Subscription) []uint {
+	result := make([]uint, 0, len(subs))
+	for _, s := range subs {
+		result = append(result, s.ID)
+	}
+	return result
+}
+
+func pick(subs []subscriptionService.Subscription, indexes ...int) []uint {
+	result := make([]uint, 0, len(indexes))
+	for _, i := range indexes {
+		result = append(result, subs[i].ID)
+	}
+	return result
+}
+
+func idString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func testCreateAndGet(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	created := create(t, repo, subscriptionService.Subscription{
+		ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1), EndDate: monthPtr(2024, 6),
+	})
+
+	if created.ID == 0 {
+		t.Fatal("ID не присвоен")
+	}
+	if created.Version != 1 {
+		t.Errorf("версия новой подписки = %d, ожидали 1", created.Version)
+	}
+	if created.CreatedAt.IsZero() {
+		t.Error("created_at не заполнен")
+	}
+
+	got, err := repo.GetSubscriptionByID(ctx, idString(created.ID))
+	if err != nil {
+		t.Fatalf("GetSubscriptionByID: %v", err)
+	}
+	if got.ServiceName != "Netflix" || got.Price != 500 || got.UserID != userA || got.Version != 1 {
+		t.Errorf("прочитано %+v", got)
+	}
+	if !got.StartDate.Equal(month(2024, 1)) || got.EndDate == nil || !got.EndDate.Equal(month(2024, 6)) {
+		t.Errorf("даты: start %v, end %v", got.StartDate, got.EndDate)
+	}
+	// Валюта не указана — подписка в валюте по умолчанию
+	if created.Currency != subscriptionService.DefaultCurrency || got.Currency != subscriptionService.DefaultCurrency {
+		t.Errorf("валюта: создано %q, прочитано %q, ожидали %q", created.Currency, got.Currency, subscriptionService.DefaultCurrency)
+	}
+
+	second := create(t, repo, subscriptionService.Subscription{ServiceName: "Spotify", Price: 200, Currency: "USD", UserID: userB, StartDate: month(2024, 2)})
+	if second.ID <= created.ID {
+		t.Errorf("ID должны расти: %d после %d", second.ID, created.ID)
+	}
+	if got, err := repo.GetSubscriptionByID(ctx, idString(second.ID)); err != nil || got.Currency != "USD" {
+		t.Errorf("валюта второй подписки: %q, %v", got.Currency, err)
+	}
+}
+
+func testGetMissing(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	_, err := repo.GetSubscriptionByID(context.Background(), "100500")
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("ожидали gorm.ErrRecordNotFound, получили %v", err)
+	}
+}
+
+func testUpdateChecksVersion(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	created := create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1)})
+
+	changed := created
+	changed.Price = 700
+	changed.Currency = "EUR"
+	changed.EndDate = monthPtr(2024, 12)
+	updated, err := repo.UpdateSubcriptionByID(ctx, changed)
+	if err != nil {
+		t.Fatalf("UpdateSubcriptionByID: %v", err)
+	}
+	if updated.Version != created.Version+1 || updated.Price != 700 || updated.Currency != "EUR" || updated.EndDate == nil {
+		t.Errorf("после обновления %+v", updated)
+	}
+
+	// Повтор со старой версией — кто-то уже изменил подписку
+	if _, err := repo.UpdateSubcriptionByID(ctx, changed); !errors.Is(err, subscriptionService.ErrVersionConflict) {
+		t.Errorf("ожидали ErrVersionConflict, получили %v", err)
+	}
+
+	// Снятие end_date тоже сохраняется
+	updated.EndDate = nil
+	cleared, err := repo.UpdateSubcriptionByID(ctx, updated)
+	if err != nil {
+		t.Fatalf("UpdateSubcriptionByID: %v", err)
+	}
+	if cleared.EndDate != nil {
+		t.Errorf("end_date не очищен: %v", cleared.EndDate)
+	}
+
+	missing := created
+	missing.ID = 100500
+	if _, err := repo.UpdateSubcriptionByID(ctx, missing); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("ожидали gorm.ErrRecordNotFound, получили %v", err)
+	}
+}
+
+func testDeleteAndRestore(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	created := create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1)})
+	id := idString(created.ID)
+
+	if err := repo.DeleteSubcriptionByID(ctx, id); err != nil {
+		t.Fatalf("DeleteSubcriptionByID: %v", err)
+	}
+	if _, err := repo.GetSubscriptionByID(ctx, id); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("удаленная подписка читается: %v", err)
+	}
+	if err := repo.DeleteSubcriptionByID(ctx, id); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("повторное удаление: ожидали gorm.ErrRecordNotFound, получили %v", err)
+	}
+
+	deleted, total, pages, err := repo.ListDeletedSubscriptions(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("ListDeletedSubscriptions: %v", err)
+	}
+	if total != 1 || pages != 1 || len(deleted) != 1 || deleted[0].ID != created.ID || deleted[0].DeletedAt.IsZero() {
+		t.Errorf("корзина: %d/%d %+v", total, pages, deleted)
+	}
+
+	restored, err := repo.RestoreSubscriptionByID(ctx, id)
+	if err != nil {
+		t.Fatalf("RestoreSubscriptionByID: %v", err)
+	}
+	if restored.Version != created.Version+1 {
+		t.Errorf("версия после восстановления = %d, ожидали %d", restored.Version, created.Version+1)
+	}
+	if _, err := repo.GetSubscriptionByID(ctx, id); err != nil {
+		t.Errorf("восстановленная подписка не читается: %v", err)
+	}
+
+	if _, err := repo.RestoreSubscriptionByID(ctx, id); !errors.Is(err, subscriptionService.ErrSubscriptionNotDeleted) {
+		t.Errorf("ожидали ErrSubscriptionNotDeleted, получили %v", err)
+	}
+	if _, err := repo.RestoreSubscriptionByID(ctx, "100500"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("ожидали gorm.ErrRecordNotFound, получили %v", err)
+	}
+}
+
+func testPurgeDeleted(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	subs := seed(t, repo)
+	for _, i := range []int{0, 2} {
+		if err := repo.DeleteSubcriptionByID(ctx, idString(subs[i].ID)); err != nil {
+			t.Fatalf("DeleteSubcriptionByID: %v", err)
+		}
+	}
+
+	purged, err := repo.PurgeDeletedSubscriptions(ctx, time.Now().UTC().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeletedSubscriptions: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("удалены свежие подписки: %d", purged)
+	}
+
+	purged, err = repo.PurgeDeletedSubscriptions(ctx, time.Now().UTC().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeletedSubscriptions: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("окончательно удалено %d, ожидали 2", purged)
+	}
+
+	_, total, _, err := repo.ListDeletedSubscriptions(ctx, 1, 10)
+	if err != nil || total != 0 {
+		t.Errorf("корзина после очистки: %d, %v", total, err)
+	}
+	if _, err := repo.RestoreSubscriptionByID(ctx, idString(subs[0].ID)); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("окончательно удаленная подписка найдена: %v", err)
+	}
+	_, total, _, err = repo.ListSubscriptions(ctx, subscriptionService.ListFilter{Page: 1, Limit: 10})
+	if err != nil || total != 3 {
+		t.Errorf("остальные подписки: %d, %v", total, err)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func testListFilters(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	subs := seed(t, repo)
+
+	tests := []struct {
+		name   string
+		filter subscriptionService.ListFilter
+		want   []uint
+	}{
+		{"без фильтров", subscriptionService.ListFilter{}, pick(subs, 0, 1, 2, 3, 4)},
+		{"user_id", subscriptionService.ListFilter{UserID: userA}, pick(subs, 0, 2, 4)},
+		{"service_name", subscriptionService.ListFilter{ServiceName: "Netflix"}, pick(subs, 0, 1)},
+		{"префикс с учетом регистра", subscriptionService.ListFilter{ServiceNamePrefix: "Yandex"}, pick(subs, 3)},
+		{"префикс со спецсимволом", subscriptionService.ListFilter{ServiceNamePrefix: "Net_"}, nil},
+		{"цена", subscriptionService.ListFilter{MinPrice: intPtr(200), MaxPrice: intPtr(400)}, pick(subs, 1, 2, 3)},
+		{"active_at", subscriptionService.ListFilter{ActiveAt: &subscriptionService.DateRange{From: month(2024, 2), To: month(2024, 2)}}, pick(subs, 0, 2)},
+		{"active_at за месяц", subscriptionService.ListFilter{ActiveAt: &subscriptionService.DateRange{From: month(2024, 2), To: day(2024, 3, 31)}}, pick(subs, 0, 1, 2)},
+		{"начало", subscriptionService.ListFilter{StartFrom: monthPtr(2024, 3), StartTo: monthPtr(2024, 12)}, pick(subs, 1, 3)},
+		{"end_from без открытых", subscriptionService.ListFilter{EndFrom: monthPtr(2024, 6)}, pick(subs, 0, 3)},
+		{"end_to", subscriptionService.ListFilter{EndTo: monthPtr(2024, 2)}, pick(subs, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Page, tt.filter.Limit = 1, 10
+			got, total, _, err := repo.ListSubscriptions(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListSubscriptions: %v", err)
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("получили %v, ожидали %v", ids(got), tt.want)
+			}
+			if total != int64(len(tt.want)) {
+				t.Errorf("total = %d, ожидали %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func testListSortAndPages(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	subs := seed(t, repo)
+
+	tests := []struct {
+		name string
+		sort []subscriptionService.SortField
+		want []uint
+	}{
+		{"по убыванию цены", []subscriptionService.SortField{{Column: "price", Desc: true}}, pick(subs, 0, 3, 1, 2, 4)},
+		{"end_date, открытые в конце", []subscriptionService.SortField{{Column: "end_date"}}, pick(subs, 2, 0, 3, 1, 4)},
+		{"-end_date, открытые в начале", []subscriptionService.SortField{{Column: "end_date", Desc: true}}, pick(subs, 1, 4, 3, 0, 2)},
+		{"user_id, затем -start_date", []subscriptionService.SortField{{Column: "user_id"}, {Column: "start_date", Desc: true}}, pick(subs, 4, 0, 2, 3, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _, err := repo.ListSubscriptions(ctx, subscriptionService.ListFilter{Page: 1, Limit: 10, Sort: tt.sort})
+			if err != nil {
+				t.Fatalf("ListSubscriptions: %v", err)
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("получили %v, ожидали %v", ids(got), tt.want)
+			}
+		})
+	}
+
+	page, total, pages, err := repo.ListSubscriptions(ctx, subscriptionService.ListFilter{Page: 3, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if total != 5 || pages != 3 || !slices.Equal(ids(page), pick(subs, 4)) {
+		t.Errorf("третья страница: %v, total %d, pages %d", ids(page), total, pages)
+	}
+
+	empty, _, _, err := repo.ListSubscriptions(ctx, subscriptionService.ListFilter{Page: 10, Limit: 2})
+	if err != nil || len(empty) != 0 {
+		t.Errorf("страница за концом списка: %v, %v", ids(empty), err)
+	}
+}
+
+func testCursor(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	subs := seed(t, repo)
+	filter := subscriptionService.ListFilter{Limit: 2}
+
+	// Вперед по страницам до конца
+	var seen []subscriptionService.Subscription
+	var cursor *subscriptionService.PageCursor
+	for range len(subs) {
+		page, err := repo.ListSubscriptionsByCursor(ctx, filter, cursor, 2)
+		if err != nil {
+			t.Fatalf("ListSubscriptionsByCursor: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		seen = append(seen, page...)
+		last := page[len(page)-1]
+		cursor = &subscriptionService.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID, Direction: subscriptionService.CursorNext}
+	}
+	if !slices.Equal(ids(seen), ids(subs)) {
+		t.Fatalf("вперед: %v, ожидали %v", ids(seen), ids(subs))
+	}
+
+	// Назад от последней подписки: ближайшие сначала
+	last := seen[len(seen)-1]
+	back, err := repo.ListSubscriptionsByCursor(ctx, filter,
+		&subscriptionService.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID, Direction: subscriptionService.CursorPrev}, 2)
+	if err != nil {
+		t.Fatalf("ListSubscriptionsByCursor: %v", err)
+	}
+	if !slices.Equal(ids(back), pick(subs, 3, 2)) {
+		t.Errorf("назад: %v, ожидали %v", ids(back), pick(subs, 3, 2))
+	}
+
+	// Фильтр применяется и при курсоре
+	filtered, err := repo.ListSubscriptionsByCursor(ctx, subscriptionService.ListFilter{UserID: userA}, nil, 10)
+	if err != nil {
+		t.Fatalf("ListSubscriptionsByCursor: %v", err)
+	}
+	if !slices.Equal(ids(filtered), pick(subs, 0, 2, 4)) {
+		t.Errorf("с фильтром: %v, ожидали %v", ids(filtered), pick(subs, 0, 2, 4))
+	}
+}
+
+func testStream(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	subs := seed(t, repo)
+	filter := subscriptionService.ListFilter{UserID: userA, Sort: []subscriptionService.SortField{{Column: "price", Desc: true}}}
+
+	var got []subscriptionService.Subscription
+	err := repo.StreamSubscriptions(ctx, filter, func(s subscriptionService.Subscription) error {
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamSubscriptions: %v", err)
+	}
+	if !slices.Equal(ids(got), pick(subs, 0, 2, 4)) {
+		t.Errorf("получили %v, ожидали %v", ids(got), pick(subs, 0, 2, 4))
+	}
+
+	errStop := errors.New("стоп")
+	calls := 0
+	err = repo.StreamSubscriptions(ctx, subscriptionService.ListFilter{}, func(subscriptionService.Subscription) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("ошибка fn: %v после %d вызовов", err, calls)
+	}
+}
+
+func testTransaction(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	existing := create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1)})
+
+	errRollback := errors.New("откат")
+	err := repo.WithinTransaction(ctx, func(tx subscriptionService.SubscriptionRepository) error {
+		create(t, tx, subscriptionService.Subscription{ServiceName: "Spotify", Price: 200, UserID: userA, StartDate: month(2024, 1)})
+		if err := tx.DeleteSubcriptionByID(ctx, idString(existing.ID)); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("ожидали ошибку fn, получили %v", err)
+	}
+
+	all, total, _, err := repo.ListSubscriptions(ctx, subscriptionService.ListFilter{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if total != 1 || all[0].ID != existing.ID {
+		t.Errorf("после отката: %v", ids(all))
+	}
+
+	var created subscriptionService.Subscription
+	err = repo.WithinTransaction(ctx, func(tx subscriptionService.SubscriptionRepository) error {
+		created = create(t, tx, subscriptionService.Subscription{ServiceName: "Spotify", Price: 200, UserID: userA, StartDate: month(2024, 1)})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTransaction: %v", err)
+	}
+	if _, err := repo.GetSubscriptionByID(ctx, idString(created.ID)); err != nil {
+		t.Errorf("изменения транзакции не сохранились: %v", err)
+	}
+}
+
+// now — момент расчета для незавершенных подписок; позже всех периодов теста
+var now = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func testSums(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	seed(t, repo)
+
+	year := subscriptionService.ParametersСalculatingSum{StartDate: month(2024, 1), EndDate: month(2024, 12)}
+	tests := []struct {
+		name   string
+		params subscriptionService.ParametersСalculatingSum
+		want   int
+	}{
+		// 6×500 + 10×300 + 2×200 + 8×400
+		{"весь 2024", year, 9600},
+		{"сервис", subscriptionService.ParametersСalculatingSum{StartDate: year.StartDate, EndDate: year.EndDate, ServiceName: "Netflix"}, 6000},
+		{"пользователь", subscriptionService.ParametersСalculatingSum{StartDate: year.StartDate, EndDate: year.EndDate, UserID: userA}, 3400},
+		// Открытые подписки считаются до now
+		{"до середины 2026", subscriptionService.ParametersСalculatingSum{StartDate: month(2026, 1), EndDate: month(2026, 12)}, 6*300 + 6*100},
+		{"пустой период", subscriptionService.ParametersСalculatingSum{StartDate: month(2020, 1), EndDate: month(2020, 12)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.SumSubscriptionsPrice(ctx, tt.params, now)
+			if err != nil {
+				t.Fatalf("SumSubscriptionsPrice: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("сумма = %d, ожидали %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func testMonthlyCostByGroup(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	seed(t, repo)
+	params := subscriptionService.ParametersСalculatingSum{StartDate: month(2024, 1), EndDate: month(2024, 12)}
+
+	rows, err := repo.MonthlyCostByGroup(ctx, params, subscriptionService.GroupByServiceName, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	totals := map[string]int64{}
+	var order []string
+	for i, row := range rows {
+		if !slices.Contains(order, row.GroupKey) {
+			order = append(order, row.GroupKey)
+		}
+		totals[row.GroupKey] += row.Total
+		if i > 0 && rows[i-1].GroupKey == row.GroupKey && !rows[i-1].Month.Before(row.Month) {
+			t.Errorf("месяцы группы %s не по порядку: %v, %v", row.GroupKey, rows[i-1].Month, row.Month)
+		}
+	}
+	if !slices.Equal(order, []string{"Netflix", "Spotify", "Yandex Plus"}) {
+		t.Errorf("порядок групп %v", order)
+	}
+	want := map[string]int64{"Netflix": 6000, "Spotify": 400, "Yandex Plus": 3200}
+	for key, total := range want {
+		if totals[key] != total {
+			t.Errorf("%s: %d, ожидали %d", key, totals[key], total)
+		}
+	}
+
+	rows, err = repo.MonthlyCostByGroup(ctx, params, subscriptionService.GroupByMonth, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	if len(rows) != 12 {
+		t.Fatalf("месяцев %d, ожидали 12", len(rows))
+	}
+	// Январь: Netflix 500 и Spotify 200; июнь: 500 + 300 + 400; июль: 300 + 400
+	for i, want := range map[int]int64{0: 700, 5: 1200, 6: 700} {
+		if !rows[i].Month.UTC().Equal(month(2024, time.Month(i+1))) || rows[i].GroupKey != rows[i].Month.UTC().Format("01-2006") || rows[i].Total != want {
+			t.Errorf("строка %d: %+v, ожидали сумму %d", i, rows[i], want)
+		}
+	}
+
+	rows, err = repo.MonthlyCostByGroup(ctx, subscriptionService.ParametersСalculatingSum{StartDate: month(2024, 1), EndDate: month(2024, 12), UserID: userB}, subscriptionService.GroupByUserID, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	var userTotal int64
+	for _, row := range rows {
+		if row.GroupKey != userB.String() {
+			t.Errorf("чужой пользователь %s", row.GroupKey)
+		}
+		userTotal += row.Total
+	}
+	if userTotal != 10*300+8*400 {
+		t.Errorf("сумма пользователя %d", userTotal)
+	}
+}
+
+func testActiveSubscriptionStats(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	seed(t, repo)
+
+	stats, err := repo.ActiveSubscriptionStats(context.Background(), month(2024, 5))
+	if err != nil {
+		t.Fatalf("ActiveSubscriptionStats: %v", err)
+	}
+	want := []subscriptionService.ServiceStats{
+		{ServiceName: "Netflix", Currency: subscriptionService.DefaultCurrency, ActiveSubscriptions: 2, MonthlyRevenue: 800},
+		{ServiceName: "Yandex Plus", Currency: subscriptionService.DefaultCurrency, ActiveSubscriptions: 1, MonthlyRevenue: 400},
+	}
+	if !slices.Equal(stats, want) {
+		t.Errorf("получили %+v, ожидали %+v", stats, want)
+	}
+}
+
+// testCurrencies проверяет, что цены в разных валютах не складываются:
+// суммы считаются в валюте из параметров, статистика делится по валютам
+func testCurrencies(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1), EndDate: dayPtr(2024, 3, 31)})
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 7, Currency: "USD", UserID: userB, StartDate: month(2024, 2), EndDate: dayPtr(2024, 5, 31)})
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Spotify", Price: 3, Currency: "USD", UserID: userA, StartDate: month(2024, 3), EndDate: dayPtr(2024, 3, 31)})
+
+	for _, mode := range []string{subscriptionService.CalculationMonthly, subscriptionService.CalculationProrated} {
+		for currency, want := range map[string]int{"RUB": 3 * 500, "USD": 4*7 + 3, "EUR": 0} {
+			params := subscriptionService.ParametersСalculatingSum{StartDate: month(2024, 1), EndDate: day(2024, 12, 31), Currency: currency, Mode: mode}
+			got, err := repo.SumSubscriptionsPrice(ctx, params, now)
+			if err != nil {
+				t.Fatalf("SumSubscriptionsPrice: %v", err)
+			}
+			if got != want {
+				t.Errorf("%s, %s: сумма = %d, ожидали %d", mode, currency, got, want)
+			}
+		}
+	}
+
+	params := subscriptionService.ParametersСalculatingSum{StartDate: month(2024, 1), EndDate: day(2024, 12, 31), Currency: "USD"}
+	rows, err := repo.MonthlyCostByGroup(ctx, params, subscriptionService.GroupByServiceName, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	totals := map[string]int64{}
+	for _, row := range rows {
+		totals[row.GroupKey] += row.Total
+	}
+	if len(totals) != 2 || totals["Netflix"] != 4*7 || totals["Spotify"] != 3 {
+		t.Errorf("суммы в USD по сервисам: %v", totals)
+	}
+
+	stats, err := repo.ActiveSubscriptionStats(ctx, month(2024, 3))
+	if err != nil {
+		t.Fatalf("ActiveSubscriptionStats: %v", err)
+	}
+	want := []subscriptionService.ServiceStats{
+		{ServiceName: "Netflix", Currency: "RUB", ActiveSubscriptions: 1, MonthlyRevenue: 500},
+		{ServiceName: "Netflix", Currency: "USD", ActiveSubscriptions: 1, MonthlyRevenue: 7},
+		{ServiceName: "Spotify", Currency: "USD", ActiveSubscriptions: 1, MonthlyRevenue: 3},
+	}
+	if !slices.Equal(stats, want) {
+		t.Errorf("получили %+v, ожидали %+v", stats, want)
+	}
+}
+
+// testBillingDay проверяет списания с середины и конца месяца:
+// подписка списывается в день начала и далее ежемесячно в тот же день,
+// в коротком месяце — в последний день
+func testBillingDay(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 100, UserID: userA, StartDate: day(2024, 1, 31)})
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Spotify", Price: 200, UserID: userA, StartDate: day(2024, 3, 15), EndDate: dayPtr(2024, 6, 10)})
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Okko", Price: 300, UserID: userB, StartDate: day(2024, 6, 20), EndDate: dayPtr(2024, 6, 30)})
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  int
+	}{
+		// Netflix: 31.01, 29.02, 31.03, …, 31.12; Spotify: 15.03, 15.04, 15.05; Okko: 20.06
+		{"весь 2024", day(2024, 1, 1), day(2024, 12, 31), 12*100 + 3*200 + 300},
+		{"февраль без 29-го", day(2024, 2, 1), day(2024, 2, 28), 0},
+		{"29 февраля", day(2024, 2, 29), day(2024, 2, 29), 100},
+		{"после списания Spotify в апреле", day(2024, 4, 16), day(2024, 12, 31), 9*100 + 200 + 300},
+		{"день до списания", day(2024, 5, 1), day(2024, 5, 14), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.SumSubscriptionsPrice(ctx, subscriptionService.ParametersСalculatingSum{StartDate: tt.start, EndDate: tt.end}, now)
+			if err != nil {
+				t.Fatalf("SumSubscriptionsPrice: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("сумма = %d, ожидали %d", got, tt.want)
+			}
+		})
+	}
+
+	rows, err := repo.MonthlyCostByGroup(ctx, subscriptionService.ParametersСalculatingSum{StartDate: day(2024, 1, 1), EndDate: day(2024, 6, 30)}, subscriptionService.GroupByMonth, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	wantRows := []int64{100, 100, 300, 300, 300, 400}
+	if len(rows) != len(wantRows) {
+		t.Fatalf("месяцев %d, ожидали %d: %+v", len(rows), len(wantRows), rows)
+	}
+	for i, want := range wantRows {
+		if !rows[i].Month.UTC().Equal(month(2024, time.Month(i+1))) || rows[i].Total != want {
+			t.Errorf("строка %d: %+v, ожидали сумму %d", i, rows[i], want)
+		}
+	}
+
+	// Spotify закончилась 10 июня, Okko началась 20-го: обе действовали в июне
+	stats, err := repo.ActiveSubscriptionStats(ctx, day(2024, 6, 15))
+	if err != nil {
+		t.Fatalf("ActiveSubscriptionStats: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Errorf("действующих сервисов в июне %d, ожидали 3: %+v", len(stats), stats)
+	}
+}
+
+// testBoundarySums фиксирует суммы на тех же граничных подписках, на которых
+// SQL сверяется с Go в repository_test.go, но с ожидаемыми значениями,
+// посчитанными вручную, — поэтому таблица проверяет и резервный расчет в Go
+// (память, SQLite), а не только совпадение SQL с ним.
+//
+// Семантика — списания в годовщину (calculateTotal): подписка списывается в
+// день начала и далее каждый месяц в тот же день (в коротком месяце — в
+// последний), дни начала и окончания периода и подписки включаются,
+// бессрочная считается по now. Это не прежний monthsBetween, который брал
+// полную цену за каждый календарный месяц между началом и концом.
+func testBoundarySums(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	for _, sub := range []subscriptionService.Subscription{
+		{ServiceName: "Netflix", Price: 301, StartDate: day(2023, 5, 1), EndDate: dayPtr(2024, 2, 29)},
+		{ServiceName: "Netflix", Price: 302, StartDate: day(2023, 4, 30), EndDate: dayPtr(2023, 5, 1)},
+		{ServiceName: "Netflix", Price: 303, StartDate: day(2024, 2, 29), EndDate: dayPtr(2024, 3, 1)},
+		{ServiceName: "Netflix", Price: 304, StartDate: day(2024, 3, 1)},
+		{ServiceName: "Netflix", Price: 305, StartDate: day(2023, 4, 29), EndDate: dayPtr(2023, 4, 30)},
+		{ServiceName: "Spotify", Price: 306, StartDate: day(2024, 1, 31)},
+		{ServiceName: "Spotify", Price: 307, StartDate: day(2024, 2, 29), EndDate: dayPtr(2025, 3, 28)},
+		{ServiceName: "Spotify", Price: 308, StartDate: day(2025, 1, 15), EndDate: dayPtr(2025, 1, 15)},
+		{ServiceName: "Kinopoisk", Price: 309, StartDate: day(2024, 12, 31), EndDate: dayPtr(2025, 1, 2)},
+		{ServiceName: "Kinopoisk", Price: 310, StartDate: day(2026, 6, 15)},
+		{ServiceName: "Kinopoisk", Price: 311, StartDate: day(2026, 6, 16)},
+	} {
+		sub.UserID = userA
+		create(t, repo, sub)
+	}
+
+	monthly, prorated := subscriptionService.CalculationMonthly, subscriptionService.CalculationProrated
+	tests := []struct {
+		name    string
+		mode    string
+		start   time.Time
+		end     time.Time
+		service string
+		want    int
+	}{
+		// 301 списывается 1.05, 302 — 30.04; у 305 списание 29.04 раньше периода
+		{"граница апреля и мая", monthly, day(2023, 4, 30), day(2023, 5, 1), "", 301 + 302},
+		// 303 и 307 начались 29.02, у 306 с 31-го числа списание переносится на 29.02
+		{"29 февраля", monthly, day(2024, 2, 29), day(2024, 2, 29), "", 303 + 306 + 307},
+		// 301: 1.02; 303: 29.02; 304: 1.03; 306: 31.01 и 29.02; 307: 29.02
+		{"середины месяцев", monthly, day(2024, 1, 15), day(2024, 3, 14), "", 301 + 303 + 304 + 2*306 + 307},
+		// 304: 1.01; 306 и 309: 31.12; 307 списывается 29-го
+		{"через новый год", monthly, day(2024, 12, 31), day(2025, 1, 2), "", 304 + 306 + 309},
+		{"через новый год, сервис", monthly, day(2024, 12, 31), day(2025, 1, 2), "Spotify", 306},
+		// 308 действовала один день и списана в день начала; 306 — 31.01, 307 — 29.01
+		{"январь, сервис", monthly, day(2025, 1, 1), day(2025, 1, 31), "Spotify", 308 + 306 + 307},
+		// now — 15.06.2026: 310 списана в день now, 311 еще не началась,
+		// у 306 июньское списание 30-го еще не наступило
+		{"текущий месяц", monthly, day(2026, 6, 1), day(2026, 6, 30), "", 304 + 310},
+		// По одному дню: 301/31, 302/30 + 302/31, 305/30 — по 10
+		{"граница апреля и мая, по дням", prorated, day(2023, 4, 30), day(2023, 5, 1), "", 10 + 10 + 10 + 10},
+		// Один день из 29: 301 и 303 — 10, 306 и 307 — 11
+		{"29 февраля, по дням", prorated, day(2024, 2, 29), day(2024, 2, 29), "", 10 + 10 + 11 + 11},
+		// 304 и 306 — 15 дней из 30 (152 и 153), 310 — один день (10)
+		{"текущий месяц, по дням", prorated, day(2026, 6, 1), day(2026, 6, 30), "", 152 + 153 + 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := subscriptionService.ParametersСalculatingSum{StartDate: tt.start, EndDate: tt.end, ServiceName: tt.service, Mode: tt.mode}
+			got, err := repo.SumSubscriptionsPrice(ctx, params, now)
+			if err != nil {
+				t.Fatalf("SumSubscriptionsPrice: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("сумма = %d, ожидали %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// testProrated проверяет пропорциональный расчет: доля цены по дням
+// действия подписки в каждом календарном месяце
+func testProrated(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	ctx := context.Background()
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 310, UserID: userA, StartDate: day(2024, 1, 1), EndDate: dayPtr(2024, 1, 2)})
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Spotify", Price: 290, UserID: userA, StartDate: day(2024, 2, 10), EndDate: dayPtr(2024, 3, 5)})
+	create(t, repo, subscriptionService.Subscription{ServiceName: "Okko", Price: 300, UserID: userB, StartDate: day(2026, 6, 1)})
+
+	params := subscriptionService.ParametersСalculatingSum{StartDate: day(2024, 1, 1), EndDate: day(2026, 12, 31), Mode: subscriptionService.CalculationProrated}
+
+	// Netflix: 2/31 × 310 = 20; Spotify: 20/29 × 290 = 200 и 5/31 × 290 ≈ 47;
+	// Okko до now: 15/30 × 300 = 150
+	total, err := repo.SumSubscriptionsPrice(ctx, params, now)
+	if err != nil {
+		t.Fatalf("SumSubscriptionsPrice: %v", err)
+	}
+	if total != 20+200+47+150 {
+		t.Errorf("сумма = %d, ожидали %d", total, 20+200+47+150)
+	}
+
+	rows, err := repo.MonthlyCostByGroup(ctx, params, subscriptionService.GroupByMonth, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	wantMonths := []time.Time{month(2024, 1), month(2024, 2), month(2024, 3), month(2026, 6)}
+	wantTotals := []int64{20, 200, 47, 150}
+	if len(rows) != len(wantMonths) {
+		t.Fatalf("строк %d, ожидали %d: %+v", len(rows), len(wantMonths), rows)
+	}
+	for i := range rows {
+		if !rows[i].Month.UTC().Equal(wantMonths[i]) || rows[i].Total != wantTotals[i] {
+			t.Errorf("строка %d: %+v, ожидали %s и %d", i, rows[i], wantMonths[i].Format("01-2006"), wantTotals[i])
+		}
+	}
+
+	rows, err = repo.MonthlyCostByGroup(ctx, params, subscriptionService.GroupByServiceName, now)
+	if err != nil {
+		t.Fatalf("MonthlyCostByGroup: %v", err)
+	}
+	byService := map[string]int64{}
+	for _, row := range rows {
+		byService[row.GroupKey] += row.Total
+	}
+	if byService["Netflix"] != 20 || byService["Spotify"] != 247 || byService["Okko"] != 150 {
+		t.Errorf("суммы по сервисам: %v", byService)
+	}
+
+	// В помесячном режиме каждое списание стоит полную цену:
+	// Netflix 1.01, Spotify 10.02 (10.03 уже после окончания), Okko 1.06
+	params.Mode = subscriptionService.CalculationMonthly
+	total, err = repo.SumSubscriptionsPrice(ctx, params, now)
+	if err != nil {
+		t.Fatalf("SumSubscriptionsPrice: %v", err)
+	}
+	if total != 310+290+300 {
+		t.Errorf("помесячная сумма = %d, ожидали %d", total, 310+290+300)
+	}
+}
+
+func testCanceledContext(t *testing.T, repo subscriptionService.SubscriptionRepository) {
+	created := create(t, repo, subscriptionService.Subscription{ServiceName: "Netflix", Price: 500, UserID: userA, StartDate: month(2024, 1)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.GetSubscriptionByID(ctx, idString(created.ID)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ожидали context.Canceled, получили %v", err)
+	}
+}
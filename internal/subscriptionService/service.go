@@ -1,8 +1,11 @@
 package subscriptionService
 
 import (
-	"errors"
-	"fmt"
+	"cmp"
+	"context"
+	"io"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,23 +13,28 @@ import (
 )
 
 type Subscription struct {
-	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ID          uint           `gorm:"primaryKey;autoIncrement;index:idx_subscriptions_created_at_id,priority:2" json:"id"`
 	ServiceName string         `gorm:"not null" json:"service_name"`
 	Price       int            `gorm:"not null" json:"price"`
+	Currency    string         `gorm:"type:char(3);not null;default:'RUB'" json:"currency"`
 	UserID      uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
-	StartDate   time.Time      `gorm:"not null" json:"start_date"`
-	EndDate     *time.Time     `json:"end_date,omitempty"`
-	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	StartDate   time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate     *time.Time     `gorm:"type:date" json:"end_date,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime;index:idx_subscriptions_created_at_id,priority:1" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	Version     int            `gorm:"not null;default:1" json:"version"` // для оптимистичной блокировки
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// RequestBody — тело создания и обновления подписки. Поля проверяет
+// validateRequestBody, а не binding: так клиент получает все ошибки сразу.
 type RequestBody struct {
-	ServiceName string    `json:"service_name" binding:"required"`
-	Price       int       `json:"price" binding:"required"`
-	UserID      uuid.UUID `json:"user_id" binding:"required"`
-	StartDate   string    `json:"start_date" binding:"required"` // формат "MM-YYYY"
-	EndDate     *string   `json:"end_date,omitempty"`            // тоже "MM-YYYY"
+	ServiceName string    `json:"service_name"`
+	Price       int       `json:"price"`
+	Currency    string    `json:"currency,omitempty" example:"RUB"` // по умолчанию RUB
+	UserID      uuid.UUID `json:"user_id"`
+	StartDate   string    `json:"start_date"`         // "YYYY-MM-DD", "YYYY-MM" или "MM-YYYY"
+	EndDate     *string   `json:"end_date,omitempty"` // месяц без дня — до конца месяца
 }
 
 type ParametersСalculatingSum struct {
@@ -34,6 +42,8 @@ type ParametersСalculatingSum struct {
 	EndDate     time.Time
 	UserID      uuid.UUID
 	ServiceName string
+	Currency    string // суммируются только подписки в этой валюте
+	Mode        string // CalculationMonthly или CalculationProrated
 }
 
 type RequestParametersСalculatingSum struct {
@@ -41,6 +51,8 @@ type RequestParametersСalculatingSum struct {
 	EndDate     string
 	UserID      string
 	ServiceName string
+	Currency    string // пусто — DefaultCurrency
+	Mode        string // пусто — CalculationMonthly
 }
 
 type PaginatedResponse struct {
@@ -48,110 +60,190 @@ type PaginatedResponse struct {
 	Meta PaginationMeta `json:"meta"`
 }
 
+// PaginationMeta описывает страницу списка. В режиме страниц заполняются
+// page/totalItems/totalPages, в режиме курсора — next_cursor/prev_cursor.
 type PaginationMeta struct {
-	Page       int   `json:"page"`
-	Limit      int   `json:"limit"`
-	TotalItems int64 `json:"totalItems"`
-	TotalPages int   `json:"totalPages"`
+	Page       int               `json:"page,omitempty"`
+	Limit      int               `json:"limit"`
+	TotalItems *int64            `json:"totalItems,omitempty"`
+	TotalPages *int              `json:"totalPages,omitempty"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
+	Filters    map[string]string `json:"filters,omitempty"`
+	Sort       string            `json:"sort,omitempty"`
 }
 
 type SubscriptionService interface {
-	ListSubscriptions(page, limit int) (PaginatedResponse, error)
-	CreateSubscriptions(r RequestBody) (Subscription, error)
-	GetSubscriptionByID(id string) (Subscription, error)
-	UpdateSubcriptionByID(r RequestBody, id string) (Subscription, error)
-	DeleteSubcriptionByID(id string) error
-	GetAmountOfsubscriptions(RequestParametersСalculatingSum) (int, error)
+	ListSubscriptions(ctx context.Context, params RequestListParameters) (PaginatedResponse, error)
+	CreateSubscriptions(ctx context.Context, r RequestBody) (Subscription, error)
+	GetSubscriptionByID(ctx context.Context, id string) (Subscription, error)
+	UpdateSubcriptionByID(ctx context.Context, r RequestBody, id string, expectedVersions []int) (Subscription, error)
+	PatchSubcriptionByID(ctx context.Context, patch []byte, id string, expectedVersions []int) (Subscription, error)
+	DeleteSubcriptionByID(ctx context.Context, id string) error
+	GetAmountOfsubscriptions(ctx context.Context, params RequestParametersСalculatingSum) (int, error)
+	GetCostBreakdown(ctx context.Context, params RequestParametersСalculatingSum, groupBy string) (CostBreakdown, error)
+	ListDeletedSubscriptions(ctx context.Context, page, limit int) (DeletedPaginatedResponse, error)
+	RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error)
+	PurgeDeletedSubscriptions(ctx context.Context) (PurgeResult, error)
+	ExecuteBatch(ctx context.Context, req BatchRequest) (BatchResult, error)
+	ImportSubscriptions(ctx context.Context, r io.Reader, dryRun bool) (ImportResult, error)
+	ExportSubscriptions(ctx context.Context, params RequestListParameters, fn func(Subscription) error) error
+	GetActiveSubscriptionStats(ctx context.Context) ([]ServiceStats, error)
 }
 
 type subService struct {
-	repo SubscriptionRepository
+	repo           SubscriptionRepository
+	purgeRetention time.Duration
 }
 
-func NewSubscriptionService(r SubscriptionRepository) SubscriptionService {
-	return &subService{repo: r}
+// Option настраивает сервис подписок
+type Option func(*subService)
+
+// WithPurgeRetention задает, сколько удаленные подписки хранятся до окончательного удаления
+func WithPurgeRetention(retention time.Duration) Option {
+	return func(s *subService) {
+		s.purgeRetention = retention
+	}
+}
+
+func NewSubscriptionService(r SubscriptionRepository, opts ...Option) SubscriptionService {
+	service := &subService{repo: r, purgeRetention: DefaultPurgeRetention}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return &tracedService{next: service}
 }
 
-func (sub *subService) ListSubscriptions(page, limit int) (PaginatedResponse, error) {
+func (sub *subService) ListSubscriptions(ctx context.Context, params RequestListParameters) (PaginatedResponse, error) {
 
-	subscriptions, totalItems, totalPages, err := sub.repo.ListSubscriptions(page, limit)
+	filter, appliedFilters, err := parseListParameters(params)
 	if err != nil {
-		return PaginatedResponse{}, fmt.Errorf("failed to get subscriptions: %w", err)
+		return PaginatedResponse{}, err
+	}
+
+	if params.CursorMode {
+		// Курсор привязан к порядку (created_at, id), другой сортировки быть не может
+		if len(filter.Sort) > 0 {
+			return PaginatedResponse{}, newValidationError(CodeInvalidSort, "sort не поддерживается при пагинации курсором")
+		}
+
+		response, err := sub.listByCursor(ctx, filter, params.Cursor)
+		if err != nil {
+			return PaginatedResponse{}, err
+		}
+		response.Meta.Filters = appliedFilters
+		return response, nil
+	}
+
+	subscriptions, totalItems, totalPages, err := sub.repo.ListSubscriptions(ctx, filter)
+	if err != nil {
+		return PaginatedResponse{}, wrapRepoError(err, "не удалось получить список подписок")
 	}
 
 	response := PaginatedResponse{
 		Data: subscriptions,
 		Meta: PaginationMeta{
-			Page:       page,
-			Limit:      limit,
-			TotalItems: totalItems,
-			TotalPages: totalPages,
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			TotalItems: &totalItems,
+			TotalPages: &totalPages,
+			Filters:    appliedFilters,
+			Sort:       formatSort(filter.Sort),
 		},
 	}
 	return response, nil
 }
 
-func (sub *subService) CreateSubscriptions(req RequestBody) (Subscription, error) {
-
-	start, err := time.Parse("01-2006", req.StartDate)
-	if err != nil {
-		return Subscription{}, errors.New("неправильный формат start_date (ожидается MM-YYYY)")
-	}
-
-	// Парсим дату окончания (если есть)
-	var end *time.Time
-	if req.EndDate != nil && *req.EndDate != "" {
-		parsedEnd, err := time.Parse("01-2006", *req.EndDate)
-		if err != nil {
-			return Subscription{}, errors.New("неправильный формат end_date (ожидается MM-YYYY)")
-		}
-		end = &parsedEnd
+func (sub *subService) CreateSubscriptions(ctx context.Context, req RequestBody) (Subscription, error) {
+	if err := validateRequestBody(req); err != nil {
+		return Subscription{}, err
 	}
 
-	subNew := Subscription{
-		ServiceName: req.ServiceName,
-		Price:       req.Price,
-		UserID:      req.UserID,
-		StartDate:   start,
-		EndDate:     end,
+	subNew, err := applyRequestBody(Subscription{}, req)
+	if err != nil {
+		return Subscription{}, err
 	}
 
-	subCreated, err := sub.repo.createSubscriptions(subNew)
+	subCreated, err := sub.repo.CreateSubscriptions(ctx, subNew)
 	if err != nil {
-		return Subscription{}, errors.New("Ошибка при создании подписки")
+		return Subscription{}, wrapRepoError(err, "ошибка при создании подписки")
 	}
 
-	return subCreated, err
+	return subCreated, nil
 
 }
 
-func (sub *subService) GetSubscriptionByID(id string) (Subscription, error) {
-	return sub.repo.getSubscriptionByID(id)
+func (sub *subService) GetSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	if err := validateID(id); err != nil {
+		return Subscription{}, err
+	}
+
+	subscription, err := sub.repo.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return Subscription{}, wrapRepoError(err, "не удалось получить подписку")
+	}
+	return subscription, nil
 }
 
-func (sub *subService) UpdateSubcriptionByID(req RequestBody, id string) (Subscription, error) {
+func (sub *subService) UpdateSubcriptionByID(ctx context.Context, req RequestBody, id string, expectedVersions []int) (Subscription, error) {
+	if err := validateID(id); err != nil {
+		return Subscription{}, err
+	}
+	if err := validateRequestBody(req); err != nil {
+		return Subscription{}, err
+	}
 
-	existingSub, err := sub.repo.getSubscriptionByID(id)
+	existingSub, err := sub.GetSubscriptionByID(ctx, id)
 	if err != nil {
 		return Subscription{}, err
 	}
-	// Парсим даты
-	start, err := time.Parse("01-2006", req.StartDate)
+
+	// Клиент прислал If-Match: проверяем, что он видел актуальную версию
+	if !versionMatches(expectedVersions, existingSub.Version) {
+		return Subscription{}, ErrVersionConflict
+	}
+
+	updatedSub, err := applyRequestBody(existingSub, req)
 	if err != nil {
 		return Subscription{}, err
 	}
 
+	savedSub, err := sub.repo.UpdateSubcriptionByID(ctx, updatedSub)
+	if err != nil {
+		return Subscription{}, wrapRepoError(err, "не удалось обновить подписку")
+	}
+	return savedSub, nil
+}
+
+// versionMatches проверяет условие If-Match: nil — версия не важна,
+// иначе текущая версия должна быть в списке ожидаемых
+func versionMatches(expected []int, current int) bool {
+	return expected == nil || slices.Contains(expected, current)
+}
+
+// applyRequestBody переносит поля запроса в подписку, разбирая даты
+func applyRequestBody(existingSub Subscription, req RequestBody) (Subscription, error) {
+	start, err := parseDate(req.StartDate, false)
+	if err != nil {
+		return Subscription{}, newValidationError(CodeInvalidStartDate, "неправильный формат start_date (ожидается "+DateFormatsHint+")")
+	}
+
+	// Парсим дату окончания (если есть)
 	var end *time.Time
 	if req.EndDate != nil && *req.EndDate != "" {
-		parsedEnd, err := time.Parse("01-2006", *req.EndDate)
+		parsedEnd, err := parseDate(*req.EndDate, true)
 		if err != nil {
-			return Subscription{}, err
+			return Subscription{}, newValidationError(CodeInvalidEndDate, "неправильный формат end_date (ожидается "+DateFormatsHint+")")
 		}
 		end = &parsedEnd
 	}
 
 	existingSub.ServiceName = req.ServiceName
 	existingSub.Price = req.Price
+	existingSub.Currency = req.Currency
+	if existingSub.Currency == "" {
+		existingSub.Currency = DefaultCurrency
+	}
 	existingSub.UserID = req.UserID
 	existingSub.StartDate = start
 	existingSub.EndDate = end
@@ -159,24 +251,67 @@ func (sub *subService) UpdateSubcriptionByID(req RequestBody, id string) (Subscr
 	return existingSub, nil
 }
 
-func (sub *subService) DeleteSubcriptionByID(id string) error {
-	return sub.repo.deleteSubcriptionByID(id)
+// validateID проверяет, что идентификатор подписки — положительное число
+func validateID(id string) error {
+	if parsed, err := strconv.ParseUint(id, 10, 64); err != nil || parsed == 0 {
+		return newValidationError(CodeInvalidID, "некорректный ID подписки")
+	}
+	return nil
 }
 
-func (subService *subService) GetAmountOfsubscriptions(params RequestParametersСalculatingSum) (int, error) {
+func (sub *subService) DeleteSubcriptionByID(ctx context.Context, id string) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
 
-	startDate, err := time.Parse("01-2006", params.StartDate)
+	if err := sub.repo.DeleteSubcriptionByID(ctx, id); err != nil {
+		return wrapRepoError(err, "не удалось удалить подписку")
+	}
+	return nil
+}
+
+func (subService *subService) GetAmountOfsubscriptions(ctx context.Context, params RequestParametersСalculatingSum) (int, error) {
+
+	validParams, err := parseSumParameters(params)
 	if err != nil {
-		return -1, errors.New("start_date должен быть в формате MM-YYYY")
+		return -1, err
+	}
 
+	total, err := subService.repo.SumSubscriptionsPrice(ctx, validParams, time.Now().UTC())
+	if err != nil {
+		return -1, wrapRepoError(err, "не удалось посчитать сумму подписок")
 	}
-	endDate, err := time.Parse("01-2006", params.EndDate)
+
+	return total, nil
+
+}
+
+// parseSumParameters проверяет и разбирает параметры расчета суммы
+func parseSumParameters(params RequestParametersСalculatingSum) (ParametersСalculatingSum, error) {
+
+	startDate, err := parseDate(params.StartDate, false)
 	if err != nil {
-		return -1, errors.New("start_date должен быть в формате MM-YYYY")
+		return ParametersСalculatingSum{}, newValidationError(CodeInvalidStartDate, "start_date должен быть датой в формате "+DateFormatsHint)
+
+	}
+	endDate, err := parseDate(params.EndDate, true)
+	if err != nil {
+		return ParametersСalculatingSum{}, newValidationError(CodeInvalidEndDate, "end_date должен быть датой в формате "+DateFormatsHint)
 	}
 
 	if endDate.Before(startDate) {
-		return -1, errors.New("end_date не может быть раньше start_date")
+		return ParametersСalculatingSum{}, newValidationError(CodeInvalidDateRange, "end_date не может быть раньше start_date")
+	}
+
+	// Цены в разных валютах складывать нельзя: сумма всегда считается в одной валюте
+	currency := cmp.Or(params.Currency, DefaultCurrency)
+	if !knownCurrencies[currency] {
+		return ParametersСalculatingSum{}, newValidationError(CodeInvalidCurrency, "неизвестная валюта, ожидается код ISO 4217, например RUB")
+	}
+
+	mode := cmp.Or(params.Mode, CalculationMonthly)
+	if mode != CalculationMonthly && mode != CalculationProrated {
+		return ParametersСalculatingSum{}, newValidationError(CodeInvalidMode, "mode должен быть одним из: monthly, prorated")
 	}
 
 	userID := uuid.Nil
@@ -184,46 +319,41 @@ func (subService *subService) GetAmountOfsubscriptions(params RequestParameters
 		var err error
 		userID, err = uuid.Parse(params.UserID)
 		if err != nil {
-			return -1, errors.New("невалидный UUID")
+			return ParametersСalculatingSum{}, newValidationError(CodeInvalidUserID, "невалидный UUID")
 		}
 	}
 
-	validParams := ParametersСalculatingSum{
+	return ParametersСalculatingSum{
 		StartDate:   startDate,
 		EndDate:     endDate,
 		UserID:      userID,
 		ServiceName: params.ServiceName,
-	}
-
-	subs, err := subService.repo.getAmountOfSubscriptions(validParams)
+		Currency:    currency,
+		Mode:        mode,
+	}, nil
+}
 
+// calculateTotal считает сумму подписок за период в памяти.
+// Это исходная реализация расчета; SumSubscriptionsPrice делает то же самое в SQL.
+func calculateTotal(subs []Subscription, params ParametersСalculatingSum, now time.Time) int {
 	total := 0
 	for _, s := range subs {
-		// Рассчитываем количество месяцев пересечения
-		subStart := s.StartDate
-		subEnd := time.Now()
-		if s.EndDate != nil {
-			subEnd = *s.EndDate
-		}
-
-		// Ограничиваем период рамками фильтра
-		if subStart.Before(startDate) {
-			subStart = startDate
+		for _, cost := range subscriptionCosts(s, params, now) {
+			total += cost.amount
 		}
-		if subEnd.After(endDate) {
-			subEnd = endDate
-		}
-
-		months := monthsBetween(subStart, subEnd)
-		total += months * s.Price
 	}
-
-	return total, nil
-
+	return total
 }
 
-func monthsBetween(start, end time.Time) int {
-	yearDiff := end.Year() - start.Year()
-	monthDiff := int(end.Month()) - int(start.Month())
-	return yearDiff*12 + monthDiff + 1 // +1, чтобы включить начальный месяц
+// chargeEnd — последний день, за который учитываются списания подписки:
+// окончание подписки (или now для бессрочной), но не позже конца периода
+func chargeEnd(s Subscription, params ParametersСalculatingSum, now time.Time) time.Time {
+	end := now.UTC()
+	if s.EndDate != nil {
+		end = s.EndDate.UTC()
+	}
+	if end.After(params.EndDate) {
+		end = params.EndDate
+	}
+	return end
 }
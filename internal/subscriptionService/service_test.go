@@ -0,0 +1,703 @@
+package subscriptionService
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthPtr(year int, m time.Month) *time.Time {
+	t := month(year, m)
+	return &t
+}
+
+func day(year int, m time.Month, d int) time.Time {
+	return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func dayPtr(year int, m time.Month, d int) *time.Time {
+	t := day(year, m, d)
+	return &t
+}
+
+// monthRange — фильтр active_at за весь месяц
+func monthRange(year int, m time.Month) *DateRange {
+	return &DateRange{From: month(year, m), To: monthEnd(month(year, m))}
+}
+
+func strPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+// errorCode возвращает код доменной ошибки или пустую строку
+func errorCode(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
+
+func TestApplyRequestBody(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		start     string
+		end       *string
+		wantStart time.Time
+		wantEnd   *time.Time
+		wantCode  string
+	}{
+		{name: "без даты окончания", start: "07-2025", wantStart: month(2025, time.July)},
+		{name: "с датой окончания", start: "01-2025", end: strPtr("12-2025"), wantStart: month(2025, time.January), wantEnd: dayPtr(2025, time.December, 31)},
+		{name: "дни", start: "2025-01-15", end: strPtr("2025-12-14"), wantStart: day(2025, time.January, 15), wantEnd: dayPtr(2025, time.December, 14)},
+		{name: "YYYY-MM", start: "2025-07", end: strPtr("2026-02"), wantStart: month(2025, time.July), wantEnd: dayPtr(2026, time.February, 28)},
+		{name: "дата и время", start: "2025-07-15T23:30:00+03:00", wantStart: day(2025, time.July, 15)},
+		{name: "пустая дата окончания", start: "01-2025", end: strPtr(""), wantStart: month(2025, time.January)},
+		{name: "однозначный месяц", start: "7-2025", wantCode: CodeInvalidStartDate},
+		{name: "несуществующий день", start: "2025-02-30", wantCode: CodeInvalidStartDate},
+		{name: "тринадцатый месяц", start: "13-2025", wantCode: CodeInvalidStartDate},
+		{name: "пустая start_date", start: "", wantCode: CodeInvalidStartDate},
+		{name: "неверная end_date", start: "01-2025", end: strPtr("31.12.2025"), wantCode: CodeInvalidEndDate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := Subscription{ID: 7, Version: 3}
+			req := RequestBody{ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: tt.start, EndDate: tt.end}
+
+			got, err := applyRequestBody(existing, req)
+			if tt.wantCode != "" {
+				if code := errorCode(err); code != tt.wantCode {
+					t.Fatalf("код ошибки = %q (%v), ожидали %q", code, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.ID != 7 || got.Version != 3 {
+				t.Errorf("ID и версия должны сохраниться: %d, %d", got.ID, got.Version)
+			}
+			if got.ServiceName != "Netflix" || got.Price != 500 || got.UserID != userID {
+				t.Errorf("поля запроса не перенесены: %+v", got)
+			}
+			if !got.StartDate.Equal(tt.wantStart) {
+				t.Errorf("start_date = %v, ожидали %v", got.StartDate, tt.wantStart)
+			}
+			switch {
+			case tt.wantEnd == nil && got.EndDate != nil:
+				t.Errorf("end_date = %v, ожидали nil", *got.EndDate)
+			case tt.wantEnd != nil && (got.EndDate == nil || !got.EndDate.Equal(*tt.wantEnd)):
+				t.Errorf("end_date = %v, ожидали %v", got.EndDate, *tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseSumParameters(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		params   RequestParametersСalculatingSum
+		want     ParametersСalculatingSum
+		wantCode string
+	}{
+		{
+			name:   "только период",
+			params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "12-2025"},
+			want:   ParametersСalculatingSum{StartDate: month(2025, time.January), EndDate: day(2025, time.December, 31), Currency: DefaultCurrency, Mode: CalculationMonthly},
+		},
+		{
+			name:   "один месяц",
+			params: RequestParametersСalculatingSum{StartDate: "02-2024", EndDate: "02-2024"},
+			want:   ParametersСalculatingSum{StartDate: month(2024, time.February), EndDate: day(2024, time.February, 29), Currency: DefaultCurrency, Mode: CalculationMonthly},
+		},
+		{
+			name:   "дни",
+			params: RequestParametersСalculatingSum{StartDate: "2025-03-10", EndDate: "2025-04-09"},
+			want:   ParametersСalculatingSum{StartDate: day(2025, time.March, 10), EndDate: day(2025, time.April, 9), Currency: DefaultCurrency, Mode: CalculationMonthly},
+		},
+		{
+			name:   "один день",
+			params: RequestParametersСalculatingSum{StartDate: "2025-03-10", EndDate: "2025-03-10"},
+			want:   ParametersСalculatingSum{StartDate: day(2025, time.March, 10), EndDate: day(2025, time.March, 10), Currency: DefaultCurrency, Mode: CalculationMonthly},
+		},
+		{
+			name:   "с пользователем и сервисом",
+			params: RequestParametersСalculatingSum{StartDate: "2025-01", EndDate: "03-2025", UserID: userID.String(), ServiceName: "Spotify"},
+			want:   ParametersСalculatingSum{StartDate: month(2025, time.January), EndDate: day(2025, time.March, 31), UserID: userID, ServiceName: "Spotify", Currency: DefaultCurrency, Mode: CalculationMonthly},
+		},
+		{
+			name:   "пропорциональный расчет",
+			params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "01-2025", Mode: "prorated"},
+			want:   ParametersСalculatingSum{StartDate: month(2025, time.January), EndDate: day(2025, time.January, 31), Currency: DefaultCurrency, Mode: CalculationProrated},
+		},
+		{
+			name:   "валюта",
+			params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "01-2025", Currency: "USD"},
+			want:   ParametersСalculatingSum{StartDate: month(2025, time.January), EndDate: day(2025, time.January, 31), Currency: "USD", Mode: CalculationMonthly},
+		},
+		{name: "неизвестная валюта", params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "12-2025", Currency: "usd"}, wantCode: CodeInvalidCurrency},
+		{name: "неизвестный режим", params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "12-2025", Mode: "daily"}, wantCode: CodeInvalidMode},
+		{name: "нет start_date", params: RequestParametersСalculatingSum{EndDate: "12-2025"}, wantCode: CodeInvalidStartDate},
+		{name: "нет end_date", params: RequestParametersСalculatingSum{StartDate: "01-2025"}, wantCode: CodeInvalidEndDate},
+		{name: "конец раньше начала", params: RequestParametersСalculatingSum{StartDate: "02-2025", EndDate: "01-2025"}, wantCode: CodeInvalidDateRange},
+		{name: "конец в прошлом году", params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "12-2024"}, wantCode: CodeInvalidDateRange},
+		{name: "конец на день раньше", params: RequestParametersСalculatingSum{StartDate: "2025-03-10", EndDate: "2025-03-09"}, wantCode: CodeInvalidDateRange},
+		{name: "невалидный UUID", params: RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "12-2025", UserID: "42"}, wantCode: CodeInvalidUserID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSumParameters(tt.params)
+			if tt.wantCode != "" {
+				if code := errorCode(err); code != tt.wantCode {
+					t.Fatalf("код ошибки = %q (%v), ожидали %q", code, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("получили %+v, ожидали %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateTotal(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+	params := ParametersСalculatingSum{StartDate: month(2025, time.January), EndDate: month(2025, time.December)}
+
+	tests := []struct {
+		name string
+		sub  Subscription
+		want int
+	}{
+		{"целиком внутри периода", Subscription{Price: 100, StartDate: month(2025, time.March), EndDate: monthPtr(2025, time.May)}, 300},
+		{"началась до периода", Subscription{Price: 100, StartDate: month(2024, time.October), EndDate: monthPtr(2025, time.February)}, 200},
+		{"закончится после периода", Subscription{Price: 100, StartDate: month(2025, time.November), EndDate: monthPtr(2026, time.March)}, 200},
+		{"шире периода", Subscription{Price: 10, StartDate: month(2020, time.January), EndDate: monthPtr(2030, time.January)}, 120},
+		{"бессрочная считается до now", Subscription{Price: 100, StartDate: month(2025, time.April)}, 300},
+		{"бессрочная с начала периода", Subscription{Price: 100, StartDate: month(2019, time.April)}, 600},
+		{"начнется после now", Subscription{Price: 100, StartDate: month(2025, time.September)}, 0},
+		{"касается первого месяца", Subscription{Price: 100, StartDate: month(2024, time.June), EndDate: monthPtr(2025, time.January)}, 100},
+		{"касается последнего месяца", Subscription{Price: 100, StartDate: month(2025, time.December), EndDate: monthPtr(2026, time.June)}, 100},
+		{"закончилась до периода", Subscription{Price: 100, StartDate: month(2024, time.January), EndDate: monthPtr(2024, time.November)}, 0},
+		{"с середины месяца", Subscription{Price: 100, StartDate: day(2025, time.March, 15), EndDate: dayPtr(2025, time.May, 10)}, 200},
+		{"списание после now еще не наступило", Subscription{Price: 100, StartDate: day(2025, time.April, 20)}, 200},
+		{"31-го числа", Subscription{Price: 100, StartDate: day(2024, time.December, 31), EndDate: dayPtr(2025, time.March, 30)}, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateTotal([]Subscription{tt.sub}, params, now); got != tt.want {
+				t.Errorf("сумма = %d, ожидали %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("несколько подписок складываются", func(t *testing.T) {
+		subs := make([]Subscription, 0, len(tests))
+		want := 0
+		for _, tt := range tests {
+			subs = append(subs, tt.sub)
+			want += tt.want
+		}
+		if got := calculateTotal(subs, params, now); got != want {
+			t.Errorf("сумма = %d, ожидали %d", got, want)
+		}
+	})
+}
+
+func TestMatchesPeriod(t *testing.T) {
+	userID := uuid.New()
+	params := ParametersСalculatingSum{StartDate: month(2025, time.March), EndDate: month(2025, time.May)}
+
+	tests := []struct {
+		name   string
+		sub    Subscription
+		params ParametersСalculatingSum
+		want   bool
+	}{
+		{"пересекается", Subscription{StartDate: month(2025, time.January), EndDate: monthPtr(2025, time.March)}, params, true},
+		{"бессрочная до периода", Subscription{StartDate: month(2020, time.January)}, params, true},
+		{"начинается в последний месяц", Subscription{StartDate: month(2025, time.May)}, params, true},
+		{"начинается после периода", Subscription{StartDate: month(2025, time.June)}, params, false},
+		{"закончилась до периода", Subscription{StartDate: month(2025, time.January), EndDate: monthPtr(2025, time.February)}, params, false},
+		{"другой пользователь", Subscription{StartDate: month(2025, time.April), UserID: uuid.New()}, ParametersСalculatingSum{StartDate: params.StartDate, EndDate: params.EndDate, UserID: userID}, false},
+		{"другой сервис", Subscription{StartDate: month(2025, time.April), ServiceName: "Netflix"}, ParametersСalculatingSum{StartDate: params.StartDate, EndDate: params.EndDate, ServiceName: "Spotify"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesPeriod(tt.sub, tt.params); got != tt.want {
+				t.Errorf("matchesPeriod = %v, ожидали %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListParameters(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name        string
+		params      RequestListParameters
+		wantApplied map[string]string
+		wantSort    string
+		wantCode    string
+	}{
+		{name: "без фильтров", params: RequestListParameters{Page: 1, Limit: 10}, wantApplied: map[string]string{}},
+		{
+			name: "все фильтры",
+			params: RequestListParameters{
+				UserID: userID.String(), ServiceName: "Netflix", ServiceNamePrefix: "Net",
+				MinPrice: "100", MaxPrice: "500", ActiveAt: "03-2025",
+				StartFrom: "01-2024", StartTo: "12-2025", EndFrom: "01-2025", EndTo: "12-2026",
+				Sort: "-price, start_date",
+			},
+			wantApplied: map[string]string{
+				"user_id": userID.String(), "service_name": "Netflix", "service_name_prefix": "Net",
+				"min_price": "100", "max_price": "500", "active_at": "03-2025",
+				"start_from": "01-2024", "start_to": "12-2025", "end_from": "01-2025", "end_to": "12-2026",
+			},
+			wantSort: "-price,start_date",
+		},
+		{name: "равные границы цены", params: RequestListParameters{MinPrice: "100", MaxPrice: "100"}, wantApplied: map[string]string{"min_price": "100", "max_price": "100"}},
+		{name: "невалидный user_id", params: RequestListParameters{UserID: "nope"}, wantCode: CodeInvalidUserID},
+		{name: "цена не число", params: RequestListParameters{MinPrice: "дешево"}, wantCode: CodeInvalidFilter},
+		{name: "min_price больше max_price", params: RequestListParameters{MinPrice: "500", MaxPrice: "100"}, wantCode: CodeInvalidFilter},
+		{name: "дата в неизвестном формате", params: RequestListParameters{ActiveAt: "03/2025"}, wantCode: CodeInvalidFilter},
+		{name: "несуществующая дата", params: RequestListParameters{EndTo: "2025-02-30"}, wantCode: CodeInvalidFilter},
+		{name: "неизвестное поле сортировки", params: RequestListParameters{Sort: "password"}, wantCode: CodeInvalidSort},
+		{name: "поле сортировки дважды", params: RequestListParameters{Sort: "price,-price"}, wantCode: CodeInvalidSort},
+		{name: "пустое поле сортировки", params: RequestListParameters{Sort: "price,"}, wantCode: CodeInvalidSort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, applied, err := parseListParameters(tt.params)
+			if tt.wantCode != "" {
+				if code := errorCode(err); code != tt.wantCode {
+					t.Fatalf("код ошибки = %q (%v), ожидали %q", code, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(applied) != len(tt.wantApplied) {
+				t.Errorf("примененные фильтры %v, ожидали %v", applied, tt.wantApplied)
+			}
+			for key, value := range tt.wantApplied {
+				if applied[key] != value {
+					t.Errorf("фильтр %s = %q, ожидали %q", key, applied[key], value)
+				}
+			}
+			if got := formatSort(filter.Sort); got != tt.wantSort {
+				t.Errorf("сортировка = %q, ожидали %q", got, tt.wantSort)
+			}
+		})
+	}
+}
+
+func TestParseListDates(t *testing.T) {
+	filter, _, err := parseListParameters(RequestListParameters{ActiveAt: "2024-02", StartFrom: "03-2024", StartTo: "03-2024", EndTo: "2025-01-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *filter.ActiveAt != (DateRange{From: month(2024, time.February), To: day(2024, time.February, 29)}) {
+		t.Errorf("active_at = %+v, ожидали весь февраль", *filter.ActiveAt)
+	}
+	// Месяц в нижней границе — его первый день, в верхней — последний
+	if !filter.StartFrom.Equal(month(2024, time.March)) || !filter.StartTo.Equal(day(2024, time.March, 31)) {
+		t.Errorf("start_from = %v, start_to = %v", *filter.StartFrom, *filter.StartTo)
+	}
+	if !filter.EndTo.Equal(day(2025, time.January, 15)) {
+		t.Errorf("end_to = %v", *filter.EndTo)
+	}
+
+	filter, _, err = parseListParameters(RequestListParameters{ActiveAt: "2024-02-10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *filter.ActiveAt != (DateRange{From: day(2024, time.February, 10), To: day(2024, time.February, 10)}) {
+		t.Errorf("active_at = %+v, ожидали один день", *filter.ActiveAt)
+	}
+}
+
+func TestListFilterMatches(t *testing.T) {
+	sub := Subscription{
+		ServiceName: "Netflix Premium",
+		Price:       300,
+		StartDate:   month(2025, time.February),
+		EndDate:     monthPtr(2025, time.June),
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   bool
+	}{
+		{"пустой фильтр", ListFilter{}, true},
+		{"префикс совпадает", ListFilter{ServiceNamePrefix: "Netflix"}, true},
+		{"префикс с другим регистром", ListFilter{ServiceNamePrefix: "netflix"}, false},
+		{"точное имя", ListFilter{ServiceName: "Netflix"}, false},
+		{"цена в границах", ListFilter{MinPrice: intPtr(300), MaxPrice: intPtr(300)}, true},
+		{"цена ниже минимума", ListFilter{MinPrice: intPtr(301)}, false},
+		{"активна в первый месяц", ListFilter{ActiveAt: monthRange(2025, time.February)}, true},
+		{"активна в последний месяц", ListFilter{ActiveAt: monthRange(2025, time.June)}, true},
+		{"не активна после окончания", ListFilter{ActiveAt: monthRange(2025, time.July)}, false},
+		{"не активна до начала", ListFilter{ActiveAt: monthRange(2025, time.January)}, false},
+		{"активна в последний день", ListFilter{ActiveAt: &DateRange{From: day(2025, time.June, 1), To: day(2025, time.June, 1)}}, true},
+		{"не активна на следующий день", ListFilter{ActiveAt: &DateRange{From: day(2025, time.June, 2), To: day(2025, time.June, 2)}}, false},
+		{"end_to без даты окончания", ListFilter{EndTo: monthPtr(2030, time.January)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(sub); got != tt.want {
+				t.Errorf("matches = %v, ожидали %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("end_to исключает бессрочные", func(t *testing.T) {
+		open := sub
+		open.EndDate = nil
+		if (ListFilter{EndTo: monthPtr(2030, time.January)}).matches(open) {
+			t.Error("бессрочная подписка не должна попадать под end_to")
+		}
+	})
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	sub := Subscription{ID: 42, CreatedAt: time.Date(2025, time.March, 4, 5, 6, 7, 891000, time.UTC)}
+
+	for _, direction := range []string{CursorNext, CursorPrev} {
+		t.Run(direction, func(t *testing.T) {
+			cursor, err := decodeCursor(encodeCursor(sub, direction))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cursor.ID != sub.ID || !cursor.CreatedAt.Equal(sub.CreatedAt) || cursor.Direction != direction {
+				t.Errorf("курсор = %+v, ожидали id=%d created_at=%v direction=%s", cursor, sub.ID, sub.CreatedAt, direction)
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		"не base64":  "%%%",
+		"не JSON":    "bm90IGpzb24",
+		"нулевой id": encodeCursor(Subscription{CreatedAt: sub.CreatedAt}, CursorNext),
+		"неизвестное движение": encodeCursor(sub, "sideways"),
+	}
+	for name, value := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decodeCursor(value); errorCode(err) != CodeInvalidCursor {
+				t.Errorf("ожидали %s, получили %v", CodeInvalidCursor, err)
+			}
+		})
+	}
+}
+
+// Тесты сервиса поверх репозитория в памяти: проверяют бизнес-правила,
+// которые не зависят от хранилища
+
+func newMemoryService(t *testing.T) SubscriptionService {
+	t.Helper()
+	return NewSubscriptionService(NewMemoryRepository())
+}
+
+func createSubscription(t *testing.T, service SubscriptionService, req RequestBody) Subscription {
+	t.Helper()
+	created, err := service.CreateSubscriptions(context.Background(), req)
+	if err != nil {
+		t.Fatalf("не удалось создать подписку: %v", err)
+	}
+	return created
+}
+
+func TestServiceUpdateVersion(t *testing.T) {
+	ctx := context.Background()
+	service := newMemoryService(t)
+	userID := uuid.New()
+
+	created := createSubscription(t, service, RequestBody{ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: "01-2025"})
+	id := idString(created.ID)
+
+	update := RequestBody{ServiceName: "Netflix", Price: 600, UserID: userID, StartDate: "01-2025", EndDate: strPtr("12-2025")}
+	updated, err := service.UpdateSubcriptionByID(ctx, update, id, []int{created.Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Price != 600 || updated.Version != created.Version+1 {
+		t.Errorf("обновление не применилось: price=%d version=%d", updated.Price, updated.Version)
+	}
+
+	// Повтор со старой версией — конфликт, подписка не меняется
+	if _, err := service.UpdateSubcriptionByID(ctx, update, id, []int{created.Version}); !errors.Is(err, ErrConflict) {
+		t.Errorf("ожидали конфликт версий, получили %v", err)
+	}
+	if _, err := service.PatchSubcriptionByID(ctx, []byte(`{"price":700}`), id, []int{created.Version}); !errors.Is(err, ErrConflict) {
+		t.Errorf("patch: ожидали конфликт версий, получили %v", err)
+	}
+
+	// Без If-Match обновление проходит по текущей версии
+	patched, err := service.PatchSubcriptionByID(ctx, []byte(`{"price":700,"end_date":null}`), id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Price != 700 || patched.EndDate != nil || patched.Version != updated.Version+1 {
+		t.Errorf("patch применился неверно: %+v", patched)
+	}
+}
+
+func TestServiceValidation(t *testing.T) {
+	ctx := context.Background()
+	service := newMemoryService(t)
+	created := createSubscription(t, service, RequestBody{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "01-2025"})
+	id := idString(created.ID)
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode string
+	}{
+		{"get: id не число", func() error { _, err := service.GetSubscriptionByID(ctx, "abc"); return err }, CodeInvalidID},
+		{"get: нулевой id", func() error { _, err := service.GetSubscriptionByID(ctx, "0"); return err }, CodeInvalidID},
+		{"get: нет такой подписки", func() error { _, err := service.GetSubscriptionByID(ctx, "999"); return err }, CodeSubscriptionNotFound},
+		{"delete: нет такой подписки", func() error { return service.DeleteSubcriptionByID(ctx, "999") }, CodeSubscriptionNotFound},
+		{"restore: не удалена", func() error { _, err := service.RestoreSubscriptionByID(ctx, id); return err }, CodeNotDeleted},
+		{"patch: не объект", func() error { _, err := service.PatchSubcriptionByID(ctx, []byte(`[1]`), id, nil); return err }, CodeInvalidPatch},
+		{"patch: неизвестное поле", func() error { _, err := service.PatchSubcriptionByID(ctx, []byte(`{"prise":1}`), id, nil); return err }, CodeInvalidPatch},
+		{"patch: удаление обязательного поля", func() error {
+			_, err := service.PatchSubcriptionByID(ctx, []byte(`{"service_name":null}`), id, nil)
+			return err
+		}, CodeValidationFailed},
+		{"сумма: неверный период", func() error {
+			_, err := service.GetAmountOfsubscriptions(ctx, RequestParametersСalculatingSum{StartDate: "05-2025", EndDate: "01-2025"})
+			return err
+		}, CodeInvalidDateRange},
+		{"разбивка: неизвестная группировка", func() error {
+			_, err := service.GetCostBreakdown(ctx, RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "05-2025"}, "city")
+			return err
+		}, CodeInvalidGroupBy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := errorCode(tt.call()); code != tt.wantCode {
+				t.Errorf("код ошибки = %q, ожидали %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServiceBatch(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	valid := &RequestBody{ServiceName: "Spotify", Price: 200, UserID: userID, StartDate: "02-2025"}
+	invalid := &RequestBody{ServiceName: "Spotify", Price: 200, UserID: userID, StartDate: "02.2025"}
+
+	tests := []struct {
+		name          string
+		mode          string
+		ops           []BatchOperation
+		wantCommitted bool
+		wantStatuses  []string
+		wantTotal     int64
+	}{
+		{
+			name:          "atomic: все успешно",
+			ops:           []BatchOperation{{Op: BatchOpCreate, Data: valid}, {Op: BatchOpCreate, Data: valid}},
+			wantCommitted: true,
+			wantStatuses:  []string{BatchItemSucceeded, BatchItemSucceeded},
+			wantTotal:     2,
+		},
+		{
+			name:         "atomic: ошибка откатывает пакет",
+			ops:          []BatchOperation{{Op: BatchOpCreate, Data: valid}, {Op: BatchOpCreate, Data: invalid}, {Op: BatchOpCreate, Data: valid}},
+			wantStatuses: []string{BatchItemRolledBack, BatchItemFailed, BatchItemSkipped},
+			wantTotal:    0,
+		},
+		{
+			name:          "best_effort: ошибка не мешает остальным",
+			mode:          BatchModeBestEffort,
+			ops:           []BatchOperation{{Op: BatchOpCreate, Data: valid}, {Op: BatchOpDelete, ID: "999"}, {Op: "upsert"}},
+			wantCommitted: true,
+			wantStatuses:  []string{BatchItemSucceeded, BatchItemFailed, BatchItemFailed},
+			wantTotal:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newMemoryService(t)
+
+			result, err := service.ExecuteBatch(ctx, BatchRequest{Mode: tt.mode, Operations: tt.ops})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Committed != tt.wantCommitted {
+				t.Errorf("committed = %v, ожидали %v", result.Committed, tt.wantCommitted)
+			}
+			for i, want := range tt.wantStatuses {
+				if got := result.Results[i].Status; got != want {
+					t.Errorf("операция %d: статус %s, ожидали %s", i, got, want)
+				}
+			}
+
+			list, err := service.ListSubscriptions(ctx, RequestListParameters{Page: 1, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *list.Meta.TotalItems != tt.wantTotal {
+				t.Errorf("в хранилище %d подписок, ожидали %d", *list.Meta.TotalItems, tt.wantTotal)
+			}
+		})
+	}
+
+	t.Run("пустой пакет", func(t *testing.T) {
+		_, err := newMemoryService(t).ExecuteBatch(ctx, BatchRequest{})
+		if code := errorCode(err); code != CodeInvalidBatch {
+			t.Errorf("код ошибки = %q, ожидали %q", code, CodeInvalidBatch)
+		}
+	})
+}
+
+func TestServiceImport(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New().String()
+	header := "service_name,price,user_id,start_date,end_date\n"
+
+	tests := []struct {
+		name         string
+		csv          string
+		dryRun       bool
+		wantImported int
+		wantErrRows  []int
+		wantCode     string
+	}{
+		{name: "все строки верны", csv: header + "Netflix,500," + userID + ",01-2025,\nSpotify,200," + userID + ",02-2025,06-2025\n", wantImported: 2},
+		{name: "dry run ничего не пишет", csv: header + "Netflix,500," + userID + ",01-2025,\n", dryRun: true},
+		{name: "ошибка в строке отменяет импорт", csv: header + "Netflix,500," + userID + ",01-2025,\nSpotify,дорого," + userID + ",02-2025,\n", wantErrRows: []int{3}},
+		{name: "колонки в другом порядке", csv: "user_id,start_date,service_name,price\n" + userID + ",03-2025,Kinopoisk,300\n", wantImported: 1},
+		{name: "нет обязательной колонки", csv: "service_name,price\nNetflix,500\n", wantCode: CodeInvalidCSV},
+		{name: "пустой файл", csv: "", wantCode: CodeInvalidCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newMemoryService(t)
+
+			result, err := service.ImportSubscriptions(ctx, strings.NewReader(tt.csv), tt.dryRun)
+			if tt.wantCode != "" {
+				if code := errorCode(err); code != tt.wantCode {
+					t.Fatalf("код ошибки = %q (%v), ожидали %q", code, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Imported != tt.wantImported {
+				t.Errorf("импортировано %d, ожидали %d", result.Imported, tt.wantImported)
+			}
+			if len(result.Errors) != len(tt.wantErrRows) {
+				t.Fatalf("ошибки %+v, ожидали строки %v", result.Errors, tt.wantErrRows)
+			}
+			for i, row := range tt.wantErrRows {
+				if result.Errors[i].Row != row {
+					t.Errorf("ошибка в строке %d, ожидали %d", result.Errors[i].Row, row)
+				}
+			}
+
+			list, err := service.ListSubscriptions(ctx, RequestListParameters{Page: 1, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *list.Meta.TotalItems != int64(tt.wantImported) {
+				t.Errorf("в хранилище %d подписок, ожидали %d", *list.Meta.TotalItems, tt.wantImported)
+			}
+		})
+	}
+}
+
+func TestServiceTrash(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	service := NewSubscriptionService(repo, WithPurgeRetention(0))
+
+	created := createSubscription(t, service, RequestBody{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "01-2025"})
+	id := idString(created.ID)
+
+	if err := service.DeleteSubcriptionByID(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetSubscriptionByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("удаленная подписка должна быть не найдена, получили %v", err)
+	}
+
+	deleted, err := service.ListDeletedSubscriptions(ctx, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted.Data) != 1 || deleted.Data[0].ID != created.ID {
+		t.Fatalf("в корзине %+v, ожидали подписку %d", deleted.Data, created.ID)
+	}
+
+	restored, err := service.RestoreSubscriptionByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != created.Version+1 {
+		t.Errorf("восстановление должно увеличить версию: %d", restored.Version)
+	}
+
+	// Срок хранения 0: после повторного удаления очистка забирает подписку сразу
+	if err := service.DeleteSubcriptionByID(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	purged, err := service.PurgeDeletedSubscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged.Purged != 1 {
+		t.Errorf("очищено %d подписок, ожидали 1", purged.Purged)
+	}
+	if _, err := service.RestoreSubscriptionByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("очищенную подписку нельзя восстановить, получили %v", err)
+	}
+}
+
+func TestServiceCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	service := newMemoryService(t)
+	_, err := service.CreateSubscriptions(ctx, RequestBody{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "01-2025"})
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("ожидали ErrCanceled, получили %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	_, err = service.GetAmountOfsubscriptions(ctx, RequestParametersСalculatingSum{StartDate: "01-2025", EndDate: "02-2025"})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("ожидали ErrTimeout, получили %v", err)
+	}
+}
+
+func idString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"rest_service/internal/subscriptionService"
+
+	sqliteDriver "github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open открывает (или создает) базу SQLite по пути path и создает в ней
+// таблицу подписок. ":memory:" — база в памяти, живущая, пока открыт *gorm.DB.
+// Драйвер написан на Go, CGO не нужен.
+//
+// Схема создается через AutoMigrate: SQL-миграции из internal/migrations
+// написаны для Postgres.
+func Open(path string, config *gorm.Config) (*gorm.DB, error) {
+	if config == nil {
+		config = &gorm.Config{}
+	}
+	// Время храним в UTC: SQLite сравнивает даты как строки
+	config.NowFunc = func() time.Time { return time.Now().UTC() }
+
+	// case_sensitive_like — чтобы фильтр по префиксу работал как LIKE в Postgres
+	dsn := "file:" + path +
+		"?_pragma=busy_timeout(5000)&_pragma=case_sensitive_like(1)&_time_format=sqlite"
+
+	db, err := gorm.Open(sqliteDriver.Open(dsn), config)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть SQLite %s: %w", path, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает одного писателя; одно соединение заодно сохраняет базу ":memory:"
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&subscriptionService.Subscription{}); err != nil {
+		return nil, fmt.Errorf("не удалось создать схему SQLite: %w", err)
+	}
+	return db, nil
+}
+
+// NewRepository создает репозиторий подписок поверх базы, открытой Open
+func NewRepository(db *gorm.DB) subscriptionService.SubscriptionRepository {
+	return subscriptionService.NewSubscriptionRepository(db)
+}
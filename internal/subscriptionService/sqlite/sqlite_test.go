@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"rest_service/internal/subscriptionService"
+	"rest_service/internal/subscriptionService/repotest"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) subscriptionService.SubscriptionRepository {
+		db, err := Open(filepath.Join(t.TempDir(), "subscriptions.db"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		return NewRepository(db)
+	})
+}
+
+func TestOpenInMemory(t *testing.T) {
+	db, err := Open(":memory:", &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.Migrator().HasTable(&subscriptionService.Subscription{}) {
+		t.Error("таблица подписок не создана")
+	}
+}
@@ -0,0 +1,29 @@
+package subscriptionService
+
+import (
+	"context"
+	"time"
+)
+
+// ServiceStats — действующие подписки одного сервиса в одной валюте в текущем месяце
+type ServiceStats struct {
+	ServiceName         string `json:"service_name"`
+	Currency            string `json:"currency"`
+	ActiveSubscriptions int64  `json:"active_subscriptions"`
+	MonthlyRevenue      int64  `json:"monthly_revenue"` // сумма цен действующих подписок в Currency, т.е. MRR сервиса
+}
+
+// GetActiveSubscriptionStats возвращает число действующих подписок и MRR по сервисам и валютам.
+// Подписка действует, если хотя бы один день текущего месяца попадает между start_date и end_date.
+func (sub *subService) GetActiveSubscriptionStats(ctx context.Context) ([]ServiceStats, error) {
+	stats, err := sub.repo.ActiveSubscriptionStats(ctx, monthStart(time.Now()))
+	if err != nil {
+		return nil, wrapRepoError(err, "не удалось посчитать действующие подписки")
+	}
+	return stats, nil
+}
+
+// activeInMonth — фильтр «действует хотя бы один день месяца»
+func activeInMonth(month time.Time) *DateRange {
+	return &DateRange{From: monthStart(month), To: monthEnd(month)}
+}
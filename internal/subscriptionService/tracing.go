@@ -0,0 +1,262 @@
+package subscriptionService
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var tracer = otel.Tracer("rest_service/internal/subscriptionService")
+
+// startSpan открывает спан слоя сервиса или репозитория
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan закрывает спан. Ошибки клиента (валидация, не найдено, конфликт)
+// записываются как события, статус Error получают только сбои сервиса.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isExpectedError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func isExpectedError(err error) bool {
+	return errors.Is(err, ErrValidation) || errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrConflict) || errors.Is(err, context.Canceled) || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func idAttr(id string) attribute.KeyValue {
+	return attribute.String("subscription.id", id)
+}
+
+// tracedService оборачивает сервис спанами; сама логика остается в subService
+type tracedService struct {
+	next SubscriptionService
+}
+
+func (t *tracedService) ListSubscriptions(ctx context.Context, params RequestListParameters) (PaginatedResponse, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.ListSubscriptions", attribute.Bool("pagination.cursor", params.CursorMode))
+	response, err := t.next.ListSubscriptions(ctx, params)
+	endSpan(span, err)
+	return response, err
+}
+
+func (t *tracedService) CreateSubscriptions(ctx context.Context, r RequestBody) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.CreateSubscriptions")
+	sub, err := t.next.CreateSubscriptions(ctx, r)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedService) GetSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.GetSubscriptionByID", idAttr(id))
+	sub, err := t.next.GetSubscriptionByID(ctx, id)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedService) UpdateSubcriptionByID(ctx context.Context, r RequestBody, id string, expectedVersions []int) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.UpdateSubcriptionByID", idAttr(id))
+	sub, err := t.next.UpdateSubcriptionByID(ctx, r, id, expectedVersions)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedService) PatchSubcriptionByID(ctx context.Context, patch []byte, id string, expectedVersions []int) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.PatchSubcriptionByID", idAttr(id))
+	sub, err := t.next.PatchSubcriptionByID(ctx, patch, id, expectedVersions)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedService) DeleteSubcriptionByID(ctx context.Context, id string) error {
+	ctx, span := startSpan(ctx, "SubscriptionService.DeleteSubcriptionByID", idAttr(id))
+	err := t.next.DeleteSubcriptionByID(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedService) GetAmountOfsubscriptions(ctx context.Context, params RequestParametersСalculatingSum) (int, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.GetAmountOfsubscriptions", attribute.String("mode", params.Mode))
+	total, err := t.next.GetAmountOfsubscriptions(ctx, params)
+	endSpan(span, err)
+	return total, err
+}
+
+func (t *tracedService) GetCostBreakdown(ctx context.Context, params RequestParametersСalculatingSum, groupBy string) (CostBreakdown, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.GetCostBreakdown", attribute.String("group_by", groupBy), attribute.String("mode", params.Mode))
+	breakdown, err := t.next.GetCostBreakdown(ctx, params, groupBy)
+	endSpan(span, err)
+	return breakdown, err
+}
+
+func (t *tracedService) ListDeletedSubscriptions(ctx context.Context, page, limit int) (DeletedPaginatedResponse, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.ListDeletedSubscriptions")
+	response, err := t.next.ListDeletedSubscriptions(ctx, page, limit)
+	endSpan(span, err)
+	return response, err
+}
+
+func (t *tracedService) RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.RestoreSubscriptionByID", idAttr(id))
+	sub, err := t.next.RestoreSubscriptionByID(ctx, id)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedService) PurgeDeletedSubscriptions(ctx context.Context) (PurgeResult, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.PurgeDeletedSubscriptions")
+	result, err := t.next.PurgeDeletedSubscriptions(ctx)
+	span.SetAttributes(attribute.Int64("subscriptions.purged", result.Purged))
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedService) ExecuteBatch(ctx context.Context, req BatchRequest) (BatchResult, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.ExecuteBatch",
+		attribute.String("batch.mode", req.Mode), attribute.Int("batch.operations", len(req.Operations)))
+	result, err := t.next.ExecuteBatch(ctx, req)
+	span.SetAttributes(attribute.Bool("batch.committed", result.Committed))
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedService) ImportSubscriptions(ctx context.Context, r io.Reader, dryRun bool) (ImportResult, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.ImportSubscriptions", attribute.Bool("import.dry_run", dryRun))
+	result, err := t.next.ImportSubscriptions(ctx, r, dryRun)
+	span.SetAttributes(attribute.Int("import.total_rows", result.TotalRows), attribute.Int("import.imported", result.Imported))
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedService) ExportSubscriptions(ctx context.Context, params RequestListParameters, fn func(Subscription) error) error {
+	ctx, span := startSpan(ctx, "SubscriptionService.ExportSubscriptions")
+	err := t.next.ExportSubscriptions(ctx, params, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedService) GetActiveSubscriptionStats(ctx context.Context) ([]ServiceStats, error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.GetActiveSubscriptionStats")
+	stats, err := t.next.GetActiveSubscriptionStats(ctx)
+	endSpan(span, err)
+	return stats, err
+}
+
+// tracedRepository оборачивает репозиторий спанами. Сами запросы к базе
+// трассирует плагин gorm, если он подключен.
+type tracedRepository struct {
+	next SubscriptionRepository
+}
+
+func (t *tracedRepository) ListSubscriptions(ctx context.Context, filter ListFilter) ([]Subscription, int64, int, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.ListSubscriptions")
+	subs, totalItems, totalPages, err := t.next.ListSubscriptions(ctx, filter)
+	endSpan(span, err)
+	return subs, totalItems, totalPages, err
+}
+
+func (t *tracedRepository) ListSubscriptionsByCursor(ctx context.Context, filter ListFilter, cursor *PageCursor, limit int) ([]Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.ListSubscriptionsByCursor")
+	subs, err := t.next.ListSubscriptionsByCursor(ctx, filter, cursor, limit)
+	endSpan(span, err)
+	return subs, err
+}
+
+func (t *tracedRepository) StreamSubscriptions(ctx context.Context, filter ListFilter, fn func(Subscription) error) error {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.StreamSubscriptions")
+	err := t.next.StreamSubscriptions(ctx, filter, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateSubscriptions(ctx context.Context, sub Subscription) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.CreateSubscriptions")
+	created, err := t.next.CreateSubscriptions(ctx, sub)
+	endSpan(span, err)
+	return created, err
+}
+
+func (t *tracedRepository) GetSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.GetSubscriptionByID", idAttr(id))
+	sub, err := t.next.GetSubscriptionByID(ctx, id)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedRepository) UpdateSubcriptionByID(ctx context.Context, sub Subscription) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.UpdateSubcriptionByID")
+	updated, err := t.next.UpdateSubcriptionByID(ctx, sub)
+	endSpan(span, err)
+	return updated, err
+}
+
+func (t *tracedRepository) DeleteSubcriptionByID(ctx context.Context, id string) error {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.DeleteSubcriptionByID", idAttr(id))
+	err := t.next.DeleteSubcriptionByID(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) ListDeletedSubscriptions(ctx context.Context, page, limit int) ([]DeletedSubscription, int64, int, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.ListDeletedSubscriptions")
+	subs, totalItems, totalPages, err := t.next.ListDeletedSubscriptions(ctx, page, limit)
+	endSpan(span, err)
+	return subs, totalItems, totalPages, err
+}
+
+func (t *tracedRepository) RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.RestoreSubscriptionByID", idAttr(id))
+	sub, err := t.next.RestoreSubscriptionByID(ctx, id)
+	endSpan(span, err)
+	return sub, err
+}
+
+func (t *tracedRepository) PurgeDeletedSubscriptions(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.PurgeDeletedSubscriptions")
+	purged, err := t.next.PurgeDeletedSubscriptions(ctx, deletedBefore)
+	endSpan(span, err)
+	return purged, err
+}
+
+func (t *tracedRepository) WithinTransaction(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.WithinTransaction")
+	err := t.next.WithinTransaction(ctx, func(repo SubscriptionRepository) error {
+		return fn(&tracedRepository{next: repo})
+	})
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) SumSubscriptionsPrice(ctx context.Context, params ParametersСalculatingSum, now time.Time) (int, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.SumSubscriptionsPrice")
+	total, err := t.next.SumSubscriptionsPrice(ctx, params, now)
+	endSpan(span, err)
+	return total, err
+}
+
+func (t *tracedRepository) MonthlyCostByGroup(ctx context.Context, params ParametersСalculatingSum, groupBy string, now time.Time) ([]MonthlyCostRow, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.MonthlyCostByGroup", attribute.String("group_by", groupBy))
+	rows, err := t.next.MonthlyCostByGroup(ctx, params, groupBy, now)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (t *tracedRepository) ActiveSubscriptionStats(ctx context.Context, month time.Time) ([]ServiceStats, error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.ActiveSubscriptionStats")
+	stats, err := t.next.ActiveSubscriptionStats(ctx, month)
+	endSpan(span, err)
+	return stats, err
+}
@@ -0,0 +1,51 @@
+package subscriptionService
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+// notFoundRepository отвечает «не найдено» на любой запрос подписки
+type notFoundRepository struct {
+	SubscriptionRepository
+}
+
+func (notFoundRepository) GetSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	return Subscription{}, gorm.ErrRecordNotFound
+}
+
+func TestTracingNestsRepositorySpansUnderService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	service := NewSubscriptionService(&tracedRepository{next: notFoundRepository{}})
+	if _, err := service.GetSubscriptionByID(context.Background(), "42"); err == nil {
+		t.Fatal("ожидалась ошибка «не найдено»")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ожидалось 2 спана, получено %d", len(spans))
+	}
+	repoSpan, serviceSpan := spans[0], spans[1]
+
+	if serviceSpan.Name() != "SubscriptionService.GetSubscriptionByID" || repoSpan.Name() != "SubscriptionRepository.GetSubscriptionByID" {
+		t.Fatalf("неожиданные спаны: %s, %s", serviceSpan.Name(), repoSpan.Name())
+	}
+	if repoSpan.Parent().SpanID() != serviceSpan.SpanContext().SpanID() {
+		t.Error("спан репозитория должен быть дочерним для спана сервиса")
+	}
+	// «Не найдено» — ответ клиенту, а не сбой сервиса
+	if serviceSpan.Status().Code == codes.Error {
+		t.Error("ошибка «не найдено» не должна помечать спан как Error")
+	}
+}
@@ -0,0 +1,69 @@
+package subscriptionService
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultPurgeRetention — срок хранения удаленных подписок по умолчанию
+const DefaultPurgeRetention = 30 * 24 * time.Hour
+
+// DeletedSubscription — мягко удаленная подписка вместе с датой удаления
+type DeletedSubscription struct {
+	Subscription
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type DeletedPaginatedResponse struct {
+	Data []DeletedSubscription `json:"data"`
+	Meta PaginationMeta        `json:"meta"`
+}
+
+// PurgeResult — итог окончательного удаления подписок из корзины
+type PurgeResult struct {
+	Purged        int64     `json:"purged"`
+	DeletedBefore time.Time `json:"deleted_before"`
+}
+
+func (sub *subService) ListDeletedSubscriptions(ctx context.Context, page, limit int) (DeletedPaginatedResponse, error) {
+
+	subscriptions, totalItems, totalPages, err := sub.repo.ListDeletedSubscriptions(ctx, page, limit)
+	if err != nil {
+		return DeletedPaginatedResponse{}, wrapRepoError(err, "не удалось получить список удаленных подписок")
+	}
+
+	return DeletedPaginatedResponse{
+		Data: subscriptions,
+		Meta: PaginationMeta{
+			Page:       page,
+			Limit:      limit,
+			TotalItems: &totalItems,
+			TotalPages: &totalPages,
+		},
+	}, nil
+}
+
+func (sub *subService) RestoreSubscriptionByID(ctx context.Context, id string) (Subscription, error) {
+	if err := validateID(id); err != nil {
+		return Subscription{}, err
+	}
+
+	restoredSub, err := sub.repo.RestoreSubscriptionByID(ctx, id)
+	if err != nil {
+		return Subscription{}, wrapRepoError(err, "не удалось восстановить подписку")
+	}
+	return restoredSub, nil
+}
+
+// PurgeDeletedSubscriptions окончательно удаляет подписки, пролежавшие
+// в корзине дольше срока хранения.
+func (sub *subService) PurgeDeletedSubscriptions(ctx context.Context) (PurgeResult, error) {
+	deletedBefore := time.Now().UTC().Add(-sub.purgeRetention)
+
+	purged, err := sub.repo.PurgeDeletedSubscriptions(ctx, deletedBefore)
+	if err != nil {
+		return PurgeResult{}, wrapRepoError(err, "не удалось очистить корзину")
+	}
+
+	return PurgeResult{Purged: purged, DeletedBefore: deletedBefore}, nil
+}
@@ -0,0 +1,133 @@
+package subscriptionService
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// MaxServiceNameLength — максимальная длина названия сервиса в символах
+const MaxServiceNameLength = 100
+
+// DefaultCurrency — валюта подписки, если клиент её не указал
+const DefaultCurrency = "RUB"
+
+// knownCurrencies — валюты (ISO 4217), в которых можно оформить подписку
+var knownCurrencies = map[string]bool{
+	"RUB": true,
+	"USD": true,
+	"EUR": true,
+	"KZT": true,
+	"BYN": true,
+	"UZS": true,
+	"AMD": true,
+	"GEL": true,
+	"CNY": true,
+	"TRY": true,
+}
+
+// serviceNamePunctuation — знаки, допустимые в названии сервиса помимо букв, цифр и пробела
+const serviceNamePunctuation = "-_.,+&'!()"
+
+// FieldError — ошибка в одном поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// fieldErrors собирает ошибки полей в порядке проверки
+type fieldErrors []FieldError
+
+func (f *fieldErrors) add(field, code, message string) {
+	*f = append(*f, FieldError{Field: field, Code: code, Message: message})
+}
+
+// err возвращает ошибку валидации со всеми найденными ошибками или nil
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(f))
+	for _, fe := range f {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+	return &Error{
+		Kind:    ErrValidation,
+		Code:    CodeValidationFailed,
+		Message: strings.Join(messages, "; "),
+		Fields:  f,
+	}
+}
+
+// validateRequestBody проверяет тело создания или обновления подписки и
+// возвращает все ошибки сразу, а не только первую. Через неё проходят
+// POST, PUT, PATCH, операции пакета и строки импорта.
+func validateRequestBody(req RequestBody) error {
+	var errs fieldErrors
+
+	validateServiceName(&errs, req.ServiceName)
+
+	switch {
+	case req.Price <= 0:
+		errs.add("price", CodeInvalidPrice, "должен быть больше нуля")
+	case req.Price > math.MaxInt32:
+		errs.add("price", CodeInvalidPrice, "слишком большое значение")
+	}
+
+	if req.UserID == uuid.Nil {
+		errs.add("user_id", CodeRequiredField, "обязательное поле")
+	}
+
+	if req.Currency != "" && !knownCurrencies[req.Currency] {
+		errs.add("currency", CodeInvalidCurrency, "неизвестная валюта, ожидается код ISO 4217, например RUB")
+	}
+
+	start, startErr := parseDate(req.StartDate, false)
+	switch {
+	case req.StartDate == "":
+		errs.add("start_date", CodeRequiredField, "обязательное поле")
+	case startErr != nil:
+		errs.add("start_date", CodeInvalidStartDate, "ожидается дата в формате "+DateFormatsHint)
+	}
+
+	if req.EndDate != nil && *req.EndDate != "" {
+		end, err := parseDate(*req.EndDate, true)
+		switch {
+		case err != nil:
+			errs.add("end_date", CodeInvalidEndDate, "ожидается дата в формате "+DateFormatsHint)
+		case startErr == nil && end.Before(start):
+			errs.add("end_date", CodeInvalidDateRange, "не может быть раньше start_date")
+		}
+	}
+
+	return errs.err()
+}
+
+// validateServiceName: непустое название без пробелов по краям, не длиннее
+// MaxServiceNameLength, из букв, цифр, пробелов и serviceNamePunctuation
+func validateServiceName(errs *fieldErrors, name string) {
+	switch {
+	case strings.TrimSpace(name) == "":
+		errs.add("service_name", CodeRequiredField, "обязательное поле")
+		return
+	case utf8.RuneCountInString(name) > MaxServiceNameLength:
+		errs.add("service_name", CodeInvalidServiceName, "не длиннее "+strconv.Itoa(MaxServiceNameLength)+" символов")
+		return
+	case strings.TrimSpace(name) != name:
+		errs.add("service_name", CodeInvalidServiceName, "не должно начинаться или заканчиваться пробелом")
+		return
+	}
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune(serviceNamePunctuation, r) {
+			errs.add("service_name", CodeInvalidServiceName, "может содержать только буквы, цифры, пробелы и знаки "+serviceNamePunctuation)
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package subscriptionService
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateRequestBody(t *testing.T) {
+	valid := RequestBody{ServiceName: "Yandex Plus", Price: 400, UserID: uuid.New(), StartDate: "07-2025"}
+
+	tests := []struct {
+		name   string
+		modify func(r *RequestBody)
+		want   []FieldError // только поле и код
+	}{
+		{name: "корректное тело", modify: func(*RequestBody) {}},
+		{name: "известная валюта", modify: func(r *RequestBody) { r.Currency = "USD" }},
+		{name: "название с пунктуацией", modify: func(r *RequestBody) { r.ServiceName = "Okko (семейная), 4K+" }},
+		{name: "название ровно 100 символов", modify: func(r *RequestBody) { r.ServiceName = strings.Repeat("я", MaxServiceNameLength) }},
+		{name: "окончание в месяц начала", modify: func(r *RequestBody) { r.EndDate = strPtr("07-2025") }},
+		{name: "пустая дата окончания", modify: func(r *RequestBody) { r.EndDate = strPtr("") }},
+		{name: "окончание в день начала", modify: func(r *RequestBody) { r.StartDate, r.EndDate = "2025-07-15", strPtr("2025-07-15") }},
+
+		{name: "нулевая цена", modify: func(r *RequestBody) { r.Price = 0 }, want: []FieldError{{Field: "price", Code: CodeInvalidPrice}}},
+		{name: "отрицательная цена", modify: func(r *RequestBody) { r.Price = -100 }, want: []FieldError{{Field: "price", Code: CodeInvalidPrice}}},
+		{name: "цена больше int32", modify: func(r *RequestBody) { r.Price = 1 << 31 }, want: []FieldError{{Field: "price", Code: CodeInvalidPrice}}},
+		{name: "пустое название", modify: func(r *RequestBody) { r.ServiceName = "" }, want: []FieldError{{Field: "service_name", Code: CodeRequiredField}}},
+		{name: "название из пробелов", modify: func(r *RequestBody) { r.ServiceName = "   " }, want: []FieldError{{Field: "service_name", Code: CodeRequiredField}}},
+		{name: "пробел в конце названия", modify: func(r *RequestBody) { r.ServiceName = "Netflix " }, want: []FieldError{{Field: "service_name", Code: CodeInvalidServiceName}}},
+		{name: "слишком длинное название", modify: func(r *RequestBody) { r.ServiceName = strings.Repeat("a", MaxServiceNameLength+1) }, want: []FieldError{{Field: "service_name", Code: CodeInvalidServiceName}}},
+		{name: "запрещенные символы", modify: func(r *RequestBody) { r.ServiceName = "<script>" }, want: []FieldError{{Field: "service_name", Code: CodeInvalidServiceName}}},
+		{name: "перевод строки", modify: func(r *RequestBody) { r.ServiceName = "Net\nflix" }, want: []FieldError{{Field: "service_name", Code: CodeInvalidServiceName}}},
+		{name: "неизвестная валюта", modify: func(r *RequestBody) { r.Currency = "XYZ" }, want: []FieldError{{Field: "currency", Code: CodeInvalidCurrency}}},
+		{name: "валюта строчными", modify: func(r *RequestBody) { r.Currency = "rub" }, want: []FieldError{{Field: "currency", Code: CodeInvalidCurrency}}},
+		{name: "нет user_id", modify: func(r *RequestBody) { r.UserID = uuid.Nil }, want: []FieldError{{Field: "user_id", Code: CodeRequiredField}}},
+		{name: "нет start_date", modify: func(r *RequestBody) { r.StartDate = "" }, want: []FieldError{{Field: "start_date", Code: CodeRequiredField}}},
+		{name: "неверная start_date", modify: func(r *RequestBody) { r.StartDate = "07.2025" }, want: []FieldError{{Field: "start_date", Code: CodeInvalidStartDate}}},
+		{name: "неверная end_date", modify: func(r *RequestBody) { r.EndDate = strPtr("13-2025") }, want: []FieldError{{Field: "end_date", Code: CodeInvalidEndDate}}},
+		{name: "окончание раньше начала", modify: func(r *RequestBody) { r.EndDate = strPtr("06-2025") }, want: []FieldError{{Field: "end_date", Code: CodeInvalidDateRange}}},
+		{name: "окончание на день раньше", modify: func(r *RequestBody) { r.StartDate, r.EndDate = "2025-07-15", strPtr("2025-07-14") }, want: []FieldError{{Field: "end_date", Code: CodeInvalidDateRange}}},
+		{
+			name: "все ошибки сразу",
+			modify: func(r *RequestBody) {
+				*r = RequestBody{ServiceName: "", Price: -1, Currency: "ABC", StartDate: "07-2025", EndDate: strPtr("01-2025")}
+			},
+			want: []FieldError{
+				{Field: "service_name", Code: CodeRequiredField},
+				{Field: "price", Code: CodeInvalidPrice},
+				{Field: "user_id", Code: CodeRequiredField},
+				{Field: "currency", Code: CodeInvalidCurrency},
+				{Field: "end_date", Code: CodeInvalidDateRange},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+
+			err := validateRequestBody(req)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("ожидали корректное тело, получили %v", err)
+				}
+				return
+			}
+
+			var domainErr *Error
+			if !errors.As(err, &domainErr) || !errors.Is(err, ErrValidation) || domainErr.Code != CodeValidationFailed {
+				t.Fatalf("ожидали ошибку %s, получили %v", CodeValidationFailed, err)
+			}
+			if len(domainErr.Fields) != len(tt.want) {
+				t.Fatalf("ошибки полей %+v, ожидали %+v", domainErr.Fields, tt.want)
+			}
+			for i, want := range tt.want {
+				got := domainErr.Fields[i]
+				if got.Field != want.Field || got.Code != want.Code || got.Message == "" {
+					t.Errorf("ошибка %d = %+v, ожидали поле %s с кодом %s", i, got, want.Field, want.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSetsDefaultCurrency(t *testing.T) {
+	service := newMemoryService(t)
+
+	created := createSubscription(t, service, RequestBody{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "01-2025"})
+	if created.Currency != DefaultCurrency {
+		t.Errorf("currency = %q, ожидали %q", created.Currency, DefaultCurrency)
+	}
+
+	created = createSubscription(t, service, RequestBody{ServiceName: "Netflix", Price: 10, Currency: "USD", UserID: uuid.New(), StartDate: "01-2025"})
+	if created.Currency != "USD" {
+		t.Errorf("currency = %q, ожидали USD", created.Currency)
+	}
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"path/filepath"
+	"testing"
+
+	"rest_service/internal/subscriptionService/sqlite"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// db.system.name берется из диалекта, а не зашит как postgresql
+func TestGormPluginSetsDBSystemFromDialector(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "tracing.db"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.Use(GormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	var one int
+	if err := db.Raw("SELECT 1").Scan(&one).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) == 0 {
+		t.Fatal("плагин не записал ни одного спана")
+	}
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "db.system.name" {
+			if got := attr.Value.AsString(); got != "sqlite" {
+				t.Errorf("db.system.name = %q, ожидалось sqlite", got)
+			}
+			return
+		}
+	}
+	t.Error("у спана нет атрибута db.system.name")
+}